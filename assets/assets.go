@@ -0,0 +1,98 @@
+package assets
+
+import (
+	"bytes"
+	"embed"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// AppIcon is the icon of the program in the system tray
+	AppIcon string = "app.ico"
+	// LogIcon is the icon of the "Show log" menu item
+	LogIcon string = "log.ico"
+	// QuitIcon is the icon of the "Quit" menu item
+	QuitIcon string = "quit.ico"
+	// CherwellLogo is the image shown in the notifications
+	CherwellLogo string = "cherwell.png"
+)
+
+// embedded holds the icons and images, so that the program does not depend on the assets folder
+//
+//go:embed app.ico log.ico quit.ico cherwell.png
+var embedded embed.FS
+
+var (
+	mutex sync.Mutex
+	// directory holds the files that replace the embedded ones, when it is set
+	directory string
+)
+
+// SetDirectory changes the directory whose files replace the embedded assets with the same name, such as for a custom branding.
+// An empty directory uses only the embedded assets.
+func SetDirectory(dir string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	directory = dir
+}
+
+// Read returns the content of the asset with the given name
+func Read(name string) ([]byte, error) {
+	if path, ok := overridePath(name); ok {
+		return ioutil.ReadFile(path)
+	}
+	return embedded.ReadFile(name)
+}
+
+// Path returns the absolute path of a file with the asset, for the notification backends that only accept a path.
+// The embedded asset is extracted to the user's cache directory, or to the temporary directory when there is none.
+func Path(name string) (string, error) {
+	if path, ok := overridePath(name); ok {
+		return filepath.Abs(path)
+	}
+
+	content, err := embedded.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	extractDir := filepath.Join(cacheDir, "cwnotifier", "assets")
+	path := filepath.Join(extractDir, name)
+
+	// the file is only written when it is missing or outdated, such as after an upgrade that changed the asset
+	if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(extractDir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// overridePath returns the path of the file that replaces the asset, if the directory is set and has one
+func overridePath(name string) (string, bool) {
+	mutex.Lock()
+	dir := directory
+	mutex.Unlock()
+
+	if dir == "" {
+		return "", false
+	}
+
+	path := filepath.Join(dir, name)
+	if fileInfo, err := os.Stat(path); err != nil || fileInfo.IsDir() {
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,138 @@
+package assets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useDirectory replaces the assets with the files of the directory, restoring the embedded ones at the end of the test
+func useDirectory(t *testing.T, dir string) {
+	SetDirectory(dir)
+	t.Cleanup(func() { SetDirectory("") })
+}
+
+// useCacheDir makes the user's cache directory a temporary one, where the assets are extracted
+func useCacheDir(t *testing.T) string {
+	cacheDir := t.TempDir()
+	for _, name := range []string{"XDG_CACHE_HOME", "LocalAppData", "HOME"} {
+		previous, wasSet := os.LookupEnv(name)
+		os.Setenv(name, cacheDir)
+		t.Cleanup(func() {
+			if wasSet {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		t.Skipf("the user's cache directory is not available. %v", err)
+	}
+	return userCacheDir
+}
+
+func mustEmbeddedFile(t *testing.T, name string) []byte {
+	content, err := embedded.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestReadEmbedded(t *testing.T) {
+	for _, name := range []string{AppIcon, LogIcon, QuitIcon, CherwellLogo} {
+		content, err := Read(name)
+		if err != nil {
+			t.Fatalf("expected %v to be embedded, got %v", name, err)
+		}
+
+		file, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, file) {
+			t.Errorf("expected the embedded %v to be the file of the assets folder", name)
+		}
+	}
+
+	if _, err := Read("missing.png"); err == nil {
+		t.Error("expected an asset that is not embedded to be reported")
+	}
+}
+
+func TestReadFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, CherwellLogo), []byte("custom logo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a folder with the name of an asset does not replace it
+	if err := os.Mkdir(filepath.Join(dir, AppIcon), 0755); err != nil {
+		t.Fatal(err)
+	}
+	useDirectory(t, dir)
+
+	if content, err := Read(CherwellLogo); err != nil || string(content) != "custom logo" {
+		t.Errorf("expected the file of the directory to replace the asset, got %q, %v", content, err)
+	}
+
+	if content, err := Read(LogIcon); err != nil || !bytes.Equal(content, mustEmbeddedFile(t, LogIcon)) {
+		t.Errorf("expected the embedded asset when the directory does not have it, got %v", err)
+	}
+	if content, err := Read(AppIcon); err != nil || !bytes.Equal(content, mustEmbeddedFile(t, AppIcon)) {
+		t.Errorf("expected the embedded asset when the directory has a folder with its name, got %v", err)
+	}
+}
+
+func TestPathExtractsTheEmbeddedAsset(t *testing.T) {
+	cacheDir := useCacheDir(t)
+	expectedPath := filepath.Join(cacheDir, "cwnotifier", "assets", CherwellLogo)
+	logo := mustEmbeddedFile(t, CherwellLogo)
+
+	path, err := Path(CherwellLogo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != expectedPath {
+		t.Errorf("expected the asset to be extracted to %v, got %v", expectedPath, path)
+	}
+	if content, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(content, logo) {
+		t.Errorf("expected the extracted file to have the embedded content, got %v", err)
+	}
+
+	// an outdated file, such as one extracted by a previous version, is replaced
+	if err := ioutil.WriteFile(path, []byte("previous logo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Path(CherwellLogo); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(path); err != nil || !bytes.Equal(content, logo) {
+		t.Errorf("expected the outdated file to be replaced, got %v", err)
+	}
+
+	if _, err := Path("missing.png"); err == nil {
+		t.Error("expected an asset that is not embedded to be reported")
+	}
+}
+
+func TestPathFromDirectory(t *testing.T) {
+	useCacheDir(t)
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, CherwellLogo), []byte("custom logo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	useDirectory(t, dir)
+
+	path, err := Path(CherwellLogo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, CherwellLogo) {
+		t.Errorf("expected the path of the file of the directory, got %v", path)
+	}
+}
@@ -0,0 +1,388 @@
+package cherwell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+const (
+	tokenPath         string = "/token"
+	searchResultsPath string = "/api/V1/getsearchresults"
+
+	defaultAuthMode   string = "Internal"
+	defaultScope      string = "Global"
+	defaultScopeOwner string = "(None)"
+	searchPageSize    int    = 500
+
+	// tokenExpirationMargin renews the token a bit before it actually expires
+	tokenExpirationMargin time.Duration = time.Minute
+)
+
+var logger = logging.New("cherwell")
+
+// Client is the data source that reads the items through the cherwell REST API, using saved searches
+type Client struct {
+	config     config.Cherwell
+	httpClient *http.Client
+	// location is the time zone of the dates returned by cherwell, which carry no zone
+	location *time.Location
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// Connect creates a client of the cherwell REST API and verifies that it is able to authenticate.
+// The dates returned by cherwell are read in the given location, which is the one of job.timezone.
+func Connect(ctx context.Context, cherwellConfig config.Cherwell, location *time.Location) (*Client, error) {
+	client := NewClient(cherwellConfig, &http.Client{Timeout: 30 * time.Second}, location)
+
+	if _, err := client.token(ctx); err != nil {
+		return nil, fmt.Errorf("Error authenticating in the cherwell REST API. %w", err)
+	}
+
+	logger.Infof("Connected successfully to the cherwell REST API.")
+	return client, nil
+}
+
+// NewClient creates a client of the cherwell REST API that uses the given http client and reads the dates in the given location
+func NewClient(cherwellConfig config.Cherwell, httpClient *http.Client, location *time.Location) *Client {
+	return &Client{
+		config:     cherwellConfig,
+		httpClient: httpClient,
+		location:   location,
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// token returns the current access token, requesting a new one when it is about to expire
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.expiresAt) {
+		return c.accessToken, nil
+	}
+
+	authMode := c.config.AuthMode
+	if authMode == "" {
+		authMode = defaultAuthMode
+	}
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {c.config.ClientID},
+		"username":   {c.config.User},
+		"password":   {c.config.Password},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(tokenPath)+"?auth_mode="+url.QueryEscape(authMode), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Token request failed with status \"%v\"", response.Status)
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	c.accessToken = token.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpirationMargin)
+	return c.accessToken, nil
+}
+
+// invalidateToken discards the current access token, so that a new one is requested
+func (c *Client) invalidateToken() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.accessToken = ""
+}
+
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.config.URL, "/") + path
+}
+
+type searchRequest struct {
+	Association      string `json:"association"`
+	BusObID          string `json:"busObId"`
+	Scope            string `json:"scope"`
+	ScopeOwner       string `json:"scopeOwner"`
+	SearchName       string `json:"searchName"`
+	IncludeAllFields bool   `json:"includeAllFields"`
+	PageNumber       int    `json:"pageNumber"`
+	PageSize         int    `json:"pageSize"`
+}
+
+type searchResponse struct {
+	BusinessObjects []businessObject `json:"businessObjects"`
+	TotalRows       int              `json:"totalRows"`
+}
+
+type businessObject struct {
+	BusObPublicID string  `json:"busObPublicId"`
+	Fields        []field `json:"fields"`
+}
+
+type field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (b businessObject) fieldValue(name string) (string, bool) {
+	for _, f := range b.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// runSearch executes a saved search and returns the business objects found, reading every page of results
+func (c *Client) runSearch(ctx context.Context, search config.CherwellSearch) ([]businessObject, error) {
+	var businessObjects []businessObject
+
+	for pageNumber := 1; ; pageNumber++ {
+		page, err := c.runSearchPage(ctx, search, pageNumber)
+		if err != nil {
+			return nil, err
+		}
+		businessObjects = append(businessObjects, page.BusinessObjects...)
+
+		if len(page.BusinessObjects) < searchPageSize || (page.TotalRows > 0 && len(businessObjects) >= page.TotalRows) {
+			return businessObjects, nil
+		}
+	}
+}
+
+// runSearchPage executes a saved search and returns the given page of results, starting at 1
+func (c *Client) runSearchPage(ctx context.Context, search config.CherwellSearch, pageNumber int) (searchResponse, error) {
+	scope := search.Scope
+	if scope == "" {
+		scope = defaultScope
+	}
+
+	scopeOwner := search.ScopeOwner
+	if scopeOwner == "" {
+		scopeOwner = defaultScopeOwner
+	}
+
+	body, err := json.Marshal(searchRequest{
+		Association:      search.BusObID,
+		BusObID:          search.BusObID,
+		Scope:            scope,
+		ScopeOwner:       scopeOwner,
+		SearchName:       search.Name,
+		IncludeAllFields: true,
+		PageNumber:       pageNumber,
+		PageSize:         searchPageSize,
+	})
+	if err != nil {
+		return searchResponse{}, err
+	}
+
+	response, err := c.post(ctx, searchResultsPath, body)
+	if err != nil {
+		return searchResponse{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(response.Body)
+		return searchResponse{}, fmt.Errorf("Search \"%v\" failed with status \"%v\": %s", search.Name, response.Status, message)
+	}
+
+	var results searchResponse
+	if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+		return searchResponse{}, err
+	}
+
+	return results, nil
+}
+
+// post sends an authenticated request, retrying once with a new token if the current one was rejected
+func (c *Client) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		token, err := c.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(path), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json")
+
+		response, err := c.httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && attempt == 1 {
+			response.Body.Close()
+			c.invalidateToken()
+			continue
+		}
+
+		return response, nil
+	}
+}
+
+// Standard cherwell field names used to fill the details of the tickets
+const (
+	priorityField    string = "Priority"
+	descriptionField string = "ShortDescription"
+	customerField    string = "CustomerDisplayName"
+	createdAtField   string = "CreatedDateTime"
+	slaRespondField  string = "SLARespondByDeadline"
+	slaDeadlineField string = "SLAResolveByDeadline"
+	ownerField       string = "OwnedBy"
+	teamField        string = "OwnedByTeam"
+)
+
+// dateLayouts are the formats in which cherwell returns dates, depending on the server's culture
+var dateLayouts = []string{
+	"1/2/2006 3:04:05 PM",
+	"02/01/2006 15:04:05",
+	time.RFC3339,
+}
+
+// parseDate reads a date returned by cherwell in the given location, returning the zero time when the value is empty or unknown
+func parseDate(value string, location *time.Location) time.Time {
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, value, location); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// ticket creates a ticket from a business object. The number is taken from the search's field
+func (c *Client) ticket(b businessObject, numberField string) datasource.Ticket {
+	number := b.BusObPublicID
+	if numberField != "" {
+		number, _ = b.fieldValue(numberField)
+	}
+
+	priority, _ := b.fieldValue(priorityField)
+	description, _ := b.fieldValue(descriptionField)
+	customer, _ := b.fieldValue(customerField)
+	createdAt, _ := b.fieldValue(createdAtField)
+	slaRespondDeadline, _ := b.fieldValue(slaRespondField)
+	slaDeadline, _ := b.fieldValue(slaDeadlineField)
+	owner, _ := b.fieldValue(ownerField)
+	team, _ := b.fieldValue(teamField)
+
+	return datasource.Ticket{
+		Number:             number,
+		Priority:           priority,
+		Description:        description,
+		Customer:           customer,
+		CreatedAt:          parseDate(createdAt, c.location),
+		SLARespondDeadline: parseDate(slaRespondDeadline, c.location),
+		SLADeadline:        parseDate(slaDeadline, c.location),
+		Owner:              owner,
+		Team:               team,
+	}
+}
+
+// search executes a saved search and returns the tickets that match the search's filters
+func (c *Client) search(ctx context.Context, notificationName string, search config.CherwellSearch, parameters map[string]string) ([]datasource.Ticket, error) {
+	var results []datasource.Ticket
+
+	logger.Debugf("Executing search \"%v\".", search.Name)
+	businessObjects, err := c.runSearch(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting %v. %w", notificationName, err)
+	}
+
+	for _, b := range businessObjects {
+		if !matchesFilters(b, search.Filters, parameters) {
+			continue
+		}
+
+		if t := c.ticket(b, search.Field); t.Number != "" {
+			results = append(results, t)
+		}
+	}
+
+	datasource.LogTickets(logger, notificationName, results)
+	return results, nil
+}
+
+// matchesFilters returns true if every filtered field of the business object is equal to the expected value.
+// Expected values that are parameter names (":team", ":email", ":userName") are replaced by the parameter's value
+func matchesFilters(b businessObject, filters map[string]string, parameters map[string]string) bool {
+	for fieldName, expected := range filters {
+		if parameter, isParameter := parameters[expected]; isParameter {
+			expected = parameter
+		}
+
+		value, isPresent := b.fieldValue(fieldName)
+		if !isPresent || !strings.EqualFold(value, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetIncidentsWithoutOwner returns the incidents without owner
+func (c *Client) GetIncidentsWithoutOwner(ctx context.Context, teamName string) ([]datasource.Ticket, error) {
+	return c.search(ctx, "incidents without owner", c.config.Searches.IncidentsWithoutOwner, map[string]string{":team": teamName})
+}
+
+// GetTasksWithoutOwner returns the tasks without owner
+func (c *Client) GetTasksWithoutOwner(ctx context.Context, teamName string, email string) ([]datasource.Ticket, error) {
+	return c.search(ctx, "tasks without owner", c.config.Searches.TasksWithoutOwner, map[string]string{":team": teamName, ":email": email})
+}
+
+// GetIncidentsWithClosedTasks returns incidents with tasks
+func (c *Client) GetIncidentsWithClosedTasks(ctx context.Context, teamName string, userName string) ([]datasource.Ticket, error) {
+	return c.search(ctx, "incidents with closed tasks", c.config.Searches.IncidentsWithClosedTasks, map[string]string{":team": teamName, ":userName": userName})
+}
+
+// GetChangesThatNeedToBeValidated returns changes that need to be validated
+func (c *Client) GetChangesThatNeedToBeValidated(ctx context.Context, userName string) ([]datasource.Ticket, error) {
+	return c.search(ctx, "changes that need to be validated", c.config.Searches.ChangesThatNeedToBeValidated, map[string]string{":userName": userName})
+}
+
+// GetChangesThatRequireUpdate returns changes that need require update
+func (c *Client) GetChangesThatRequireUpdate(ctx context.Context, userName string) ([]datasource.Ticket, error) {
+	return c.search(ctx, "changes that require update", c.config.Searches.ChangesThatRequireUpdate, map[string]string{":userName": userName})
+}
+
+// Close releases the idle connections of the client
+func (c *Client) Close() {
+	c.httpClient.CloseIdleConnections()
+	logger.Infof("Cherwell REST API client was closed.")
+}
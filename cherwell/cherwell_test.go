@@ -0,0 +1,238 @@
+package cherwell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+)
+
+// stubServer stands in for the cherwell REST API. It serves the given business objects, paginated, through any saved search.
+type stubServer struct {
+	businessObjects []businessObject
+
+	mutex sync.Mutex
+	// tokens counts the tokens issued, each one is named after its number
+	tokens int
+	// rejectedTokens are answered with 401, as if they had expired in the server
+	rejectedTokens map[string]bool
+	// pages holds the page numbers requested, in order
+	pages []int
+}
+
+func (s *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch r.URL.Path {
+	case tokenPath:
+		if r.FormValue("grant_type") != "password" || r.FormValue("username") != "user" || r.FormValue("password") != "secret" {
+			http.Error(w, "invalid credentials", http.StatusBadRequest)
+			return
+		}
+		s.tokens++
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: fmt.Sprintf("token-%v", s.tokens), ExpiresIn: 3600})
+	case searchResultsPath:
+		token := r.Header.Get("Authorization")
+		if token == "" || s.rejectedTokens[token] {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var request searchRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.pages = append(s.pages, request.PageNumber)
+
+		start := (request.PageNumber - 1) * request.PageSize
+		end := start + request.PageSize
+		if start > len(s.businessObjects) {
+			start = len(s.businessObjects)
+		}
+		if end > len(s.businessObjects) {
+			end = len(s.businessObjects)
+		}
+		json.NewEncoder(w).Encode(searchResponse{BusinessObjects: s.businessObjects[start:end], TotalRows: len(s.businessObjects)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newStubClient(t *testing.T, stub *stubServer, searches config.CherwellSearches, location *time.Location) *Client {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	cherwellConfig := config.Cherwell{URL: server.URL + "/", User: "user", Password: "secret", Searches: searches}
+	return NewClient(cherwellConfig, server.Client(), location)
+}
+
+func incident(number string, team string, owner string) businessObject {
+	return businessObject{
+		BusObPublicID: number,
+		Fields: []field{
+			{Name: priorityField, Value: "1"},
+			{Name: teamField, Value: team},
+			{Name: ownerField, Value: owner},
+		},
+	}
+}
+
+func TestTokenRefreshOnUnauthorized(t *testing.T) {
+	stub := &stubServer{businessObjects: []businessObject{incident("1", "Support", "")}}
+	client := newStubClient(t, stub, config.CherwellSearches{IncidentsWithoutOwner: config.CherwellSearch{Name: "incidents"}}, time.UTC)
+
+	if _, err := client.GetIncidentsWithoutOwner(context.Background(), "Support"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server rejects the current token before it expires, such as after a restart of the cherwell server
+	stub.mutex.Lock()
+	stub.rejectedTokens = map[string]bool{"Bearer token-1": true}
+	stub.mutex.Unlock()
+
+	tickets, err := client.GetIncidentsWithoutOwner(context.Background(), "Support")
+	if err != nil {
+		t.Fatalf("expected a new token to be requested, got %v", err)
+	}
+	if len(tickets) != 1 || stub.tokens != 2 {
+		t.Errorf("expected 1 ticket with 2 tokens issued, got %v tickets and %v tokens", len(tickets), stub.tokens)
+	}
+
+	// a new token that is also rejected is an error, instead of a loop
+	stub.mutex.Lock()
+	stub.rejectedTokens["Bearer token-2"] = true
+	stub.rejectedTokens["Bearer token-3"] = true
+	stub.mutex.Unlock()
+
+	if _, err := client.GetIncidentsWithoutOwner(context.Background(), "Support"); err == nil {
+		t.Error("expected an error when every token is rejected")
+	}
+}
+
+func TestSearchReadsEveryPage(t *testing.T) {
+	stub := &stubServer{}
+	for i := 0; i < 2*searchPageSize+1; i++ {
+		stub.businessObjects = append(stub.businessObjects, incident(fmt.Sprint(i), "Support", ""))
+	}
+	client := newStubClient(t, stub, config.CherwellSearches{IncidentsWithoutOwner: config.CherwellSearch{Name: "incidents"}}, time.UTC)
+
+	tickets, err := client.GetIncidentsWithoutOwner(context.Background(), "Support")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != len(stub.businessObjects) {
+		t.Errorf("expected %v tickets, got %v", len(stub.businessObjects), len(tickets))
+	}
+	if fmt.Sprint(stub.pages) != "[1 2 3]" {
+		t.Errorf("expected pages [1 2 3] to be requested, got %v", stub.pages)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	stub := &stubServer{businessObjects: []businessObject{
+		incident("1", "Support", ""),
+		incident("2", "support", "ana@example.com"),
+		incident("3", "Network", ""),
+		{BusObPublicID: "4"},
+	}}
+	searches := config.CherwellSearches{
+		IncidentsWithoutOwner: config.CherwellSearch{Name: "incidents", Filters: map[string]string{teamField: ":team"}},
+		TasksWithoutOwner:     config.CherwellSearch{Name: "tasks", Filters: map[string]string{teamField: ":team", ownerField: ":email"}},
+		ChangesThatRequireUpdate: config.CherwellSearch{Name: "changes", Field: "ChangeID", Filters: map[string]string{
+			priorityField: "1",
+		}},
+	}
+	client := newStubClient(t, stub, searches, time.UTC)
+
+	tests := []struct {
+		name     string
+		get      func() ([]datasource.Ticket, error)
+		expected string
+	}{
+		{"team", func() ([]datasource.Ticket, error) {
+			return client.GetIncidentsWithoutOwner(context.Background(), "Support")
+		}, "[1 2]"},
+		{"team and email", func() ([]datasource.Ticket, error) {
+			return client.GetTasksWithoutOwner(context.Background(), "Support", "ana@example.com")
+		}, "[2]"},
+		{"fixed value, without the number field", func() ([]datasource.Ticket, error) {
+			return client.GetChangesThatRequireUpdate(context.Background(), "ana")
+		}, "[]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if numbers := ticketNumbers(result); numbers != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, numbers)
+			}
+		})
+	}
+}
+
+func ticketNumbers(tickets []datasource.Ticket) string {
+	numbers := []string{}
+	for _, t := range tickets {
+		numbers = append(numbers, t.Number)
+	}
+	return fmt.Sprint(numbers)
+}
+
+func TestParseDate(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("the time zone database is not available")
+	}
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"1/21/2021 2:30:00 PM", time.Date(2021, 1, 21, 14, 30, 0, 0, saoPaulo)},
+		{"21/01/2021 14:30:00", time.Date(2021, 1, 21, 14, 30, 0, 0, saoPaulo)},
+		{"2021-01-21T14:30:00Z", time.Date(2021, 1, 21, 14, 30, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"not a date", time.Time{}},
+	}
+
+	for _, test := range tests {
+		if date := parseDate(test.value, saoPaulo); !date.Equal(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.value, test.expected, date)
+		}
+	}
+}
+
+func TestTicketDatesInLocation(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("the time zone database is not available")
+	}
+
+	b := incident("1", "Support", "")
+	b.Fields = append(b.Fields, field{Name: slaDeadlineField, Value: "1/21/2021 6:00:00 PM"})
+	stub := &stubServer{businessObjects: []businessObject{b}}
+	client := newStubClient(t, stub, config.CherwellSearches{IncidentsWithoutOwner: config.CherwellSearch{Name: "incidents"}}, saoPaulo)
+
+	tickets, err := client.GetIncidentsWithoutOwner(context.Background(), "Support")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 18:00 in São Paulo (UTC-3) is 21:00 UTC, regardless of the machine's time zone
+	expected := time.Date(2021, 1, 21, 21, 0, 0, 0, time.UTC)
+	if len(tickets) != 1 || !tickets[0].SLADeadline.Equal(expected) {
+		t.Errorf("expected the SLA deadline %v, got %v", expected, tickets)
+	}
+}
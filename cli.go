@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/logging"
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+	"github.com/pedroppinheiro/cwnotifier/secrets"
+	"github.com/pedroppinheiro/cwnotifier/tracker"
+)
+
+const usage string = `Usage: cwnotifier [--config config.yaml] [--log-unredacted] <command> [options]
+
+Commands:
+  run                  runs the program in the system tray. This is the default command
+                       --headless   runs without the system tray, until interrupted or terminated
+  check --once         checks every enabled notification once and prints the items found, without notifying
+                       --format text|json   output format, defaults to text
+  validate-config      validates the configuration file
+  test-notify <type>   emits a sample notification through the configured backends. Types: %v
+  set-password         saves the database password, read from the standard input, in the operating system's secret store
+  version              prints the program's version
+`
+
+// configurationLocation is the configuration file, which can be changed by the --config flag
+var configurationLocation = defaultYAMLName
+
+// logUnredacted disables the masking of passwords, e-mails and names in the log, when set by the --log-unredacted flag
+var logUnredacted bool
+
+// sampleItem is the item shown in the sample notifications
+const sampleItem string = "12345 [P1] Exemplo de chamado - Fulano de Tal"
+
+// sampleNotifications emits a sample of each notification, by type, for the test-notify command
+var sampleNotifications = map[string]func(configuration config.Configuration){
+	"incidentsWithoutOwner": func(configuration config.Configuration) {
+		notifier.NotifyIncidentsWithoutOwner("", []string{sampleItem}, sampleActions(configuration.Portal.IncidentURL), notifier.SeverityUrgent)
+	},
+	"tasksWithoutOwner": func(configuration config.Configuration) {
+		notifier.NotifyTasksWithoutOwner("", []string{sampleItem}, sampleActions(configuration.Portal.IncidentURL), notifier.SeverityUrgent)
+	},
+	"incidentsWithClosedTasks": func(configuration config.Configuration) {
+		notifier.NotifyIncidentsWithClosedTasks("", []string{sampleItem}, sampleActions(configuration.Portal.IncidentURL), notifier.SeverityWarning)
+	},
+	"changesThatNeedToBeValidated": func(configuration config.Configuration) {
+		notifier.NotifyChangesThatNeedToBeValidated("", []string{sampleItem}, sampleActions(configuration.Portal.ChangeURL), notifier.SeverityWarning)
+	},
+	"changesThatRequireUpdate": func(configuration config.Configuration) {
+		notifier.NotifyChangesThatRequireUpdate("", []string{sampleItem}, sampleActions(configuration.Portal.ChangeURL), notifier.SeverityWarning)
+	},
+	"rule": func(configuration config.Configuration) {
+		notifier.NotifyRule("", "Exemplo de regra", "Esta é uma notificação de teste", []string{sampleItem})
+	},
+	"outage": func(configuration config.Configuration) {
+		notifier.NotifyOutage(time.Now())
+	},
+	"connectionRestored": func(configuration config.Configuration) {
+		notifier.NotifyConnectionRestored()
+	},
+	"start": func(configuration config.Configuration) {
+		notifier.NotifyProgramStart()
+	},
+	"error": func(configuration config.Configuration) {
+		notifier.NotifyError()
+	},
+}
+
+func sampleActions(urlTemplate string) []notifier.Action {
+	if urlTemplate == "" {
+		return nil
+	}
+	return []notifier.Action{{Label: "Abrir 12345", URL: config.TicketURL(urlTemplate, "12345")}}
+}
+
+func sampleNotificationTypes() string {
+	var types []string
+	for name := range sampleNotifications {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// newFlagSet creates the flags of a command. Every command accepts the --config flag
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&configurationLocation, "config", configurationLocation, "the configuration file")
+	flags.BoolVar(&logUnredacted, "log-unredacted", logUnredacted, "writes passwords, e-mails and names to the log without masking them")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), usage, sampleNotificationTypes())
+	}
+	return flags
+}
+
+// runCommand executes the command given in the arguments and returns the exit code of the program
+func runCommand(args []string) int {
+	flags := newFlagSet("cwnotifier")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	command := "run"
+	args = flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		return runProgram(args)
+	case "check":
+		return checkOnce(args, os.Stdout)
+	case "validate-config":
+		return validateConfiguration(args)
+	case "test-notify":
+		return testNotify(args)
+	case "set-password":
+		return setPassword(args)
+	case "version":
+		fmt.Println("CWNotifier", version)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command \"%v\".\n\n", command)
+	fmt.Fprintf(os.Stderr, usage, sampleNotificationTypes())
+	return 2
+}
+
+func runProgram(args []string) int {
+	flags := newFlagSet("run")
+	headless := flags.Bool("headless", false, "runs without the system tray")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *headless {
+		return runHeadless()
+	}
+	return runTray()
+}
+
+func validateConfiguration(args []string) int {
+	if err := newFlagSet("validate-config").Parse(args); err != nil {
+		return 2
+	}
+
+	if _, err := readConfiguration(configurationLocation); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("The configuration file \"%v\" is valid.\n", configurationLocation)
+	return 0
+}
+
+// setPassword saves the database password in the secret store, from where it is read when database.passwordStore is true
+func setPassword(args []string) int {
+	if err := newFlagSet("set-password").Parse(args); err != nil {
+		return 2
+	}
+
+	configuration, err := readConfiguration(configurationLocation)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := applySettings(configuration); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	account := configuration.Database.PasswordAccount()
+	fmt.Fprintf(os.Stderr, "Password of %v: ", account)
+	password, err := readPassword(os.Stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading the password.", err)
+		return 1
+	}
+
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "The password cannot be empty.")
+		return 1
+	}
+	logging.AddSecret(password)
+
+	if err := secrets.Set(account, password); err != nil {
+		fmt.Fprintln(os.Stderr, "Error saving the password.", err)
+		return 1
+	}
+
+	fmt.Printf("The password of %v was saved.\n", account)
+	if !configuration.Database.PasswordStore {
+		fmt.Println("Set database.passwordStore to true, removing database.password, to use it.")
+	}
+	return 0
+}
+
+// readLine reads a line from the file, without the line break. The last line may have no line break.
+func readLine(file *os.File) (string, error) {
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func testNotify(args []string) int {
+	flags := newFlagSet("test-notify")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "test-notify requires the type of the notification: %v\n", sampleNotificationTypes())
+		return 2
+	}
+
+	emit, isPresent := sampleNotifications[flags.Arg(0)]
+	if !isPresent {
+		fmt.Fprintf(os.Stderr, "Unknown notification type \"%v\". Should be one of: %v\n", flags.Arg(0), sampleNotificationTypes())
+		return 2
+	}
+
+	configuration, err := readConfiguration(configurationLocation)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := applySettings(configuration); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	backend, err := notifier.New(configuration.Notifier)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating the notification backend.", err)
+		return 1
+	}
+	notifier.SetNotifier(backend)
+
+	emit(configuration)
+	return 0
+}
+
+// checkResult is what a check found, as printed by the check command
+type checkResult struct {
+	Check string   `json:"check"`
+	Items []string `json:"items"`
+	Error string   `json:"error,omitempty"`
+}
+
+// checkOnce executes every enabled check once, regardless of the calendar, and prints the items found instead of notifying them
+func checkOnce(args []string, output io.Writer) int {
+	flags := newFlagSet("check")
+	once := flags.Bool("once", false, "checks once and exits")
+	format := flags.String("format", "text", "the output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !*once {
+		fmt.Fprintln(os.Stderr, "check only supports --once. Use the run command to check continuously.")
+		return 2
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid format \"%v\". Should be \"text\" or \"json\".\n", *format)
+		return 2
+	}
+
+	configuration, err := readConfiguration(configurationLocation)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := applySettings(configuration); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// the notifications are recorded instead of emitted and the tracker is kept only in memory,
+	// so that every item found is reported and the state file is left untouched
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopOnSignal(cancel)
+
+	recorder := &notifier.Recorder{}
+	notifier.SetNotifier(recorder)
+	notificationTracker = tracker.New(configuration.Job.GetEscalationInterval())
+	defer closeDataSource()
+
+	results := []checkResult{}
+	exitCode := 0
+	for _, c := range scheduledChecks(configuration) {
+		recorder.Reset()
+
+		result := checkResult{Check: c.name, Items: []string{}}
+		if err := checkCherwell(ctx, configuration, c.run); err != nil {
+			result.Error = err.Error()
+			exitCode = 1
+		}
+
+		for _, notification := range recorder.Notifications() {
+			result.Items = append(result.Items, notification.Items...)
+		}
+		results = append(results, result)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return exitCode
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(output, "%v: error: %v\n", result.Check, result.Error)
+		case len(result.Items) == 0:
+			fmt.Fprintf(output, "%v: nothing found\n", result.Check)
+		default:
+			fmt.Fprintf(output, "%v: %v item(s)\n", result.Check, len(result.Items))
+			for _, item := range result.Items {
+				fmt.Fprintf(output, "    %v\n", item)
+			}
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+// useCommandFlags restores the values set by the flags of the commands at the end of the test
+func useCommandFlags(t *testing.T) {
+	previousLocation, previousUnredacted := configurationLocation, logUnredacted
+	t.Cleanup(func() {
+		configurationLocation, logUnredacted = previousLocation, previousUnredacted
+		logging.SetRedaction(!logUnredacted)
+	})
+}
+
+// writeCommandConfiguration writes a valid configuration file, which reads an empty fixture and notifies through the log.
+// Every notification is enabled, as by default.
+func writeCommandConfiguration(t *testing.T) string {
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixture.yaml")
+	if err := ioutil.WriteFile(fixture, []byte("incidentsWithoutOwner: []\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	yamlLocation := filepath.Join(dir, "config.yaml")
+	content := fmt.Sprintf(`dataSource: "fixture"
+fixture: %q
+user:
+  team: "Support"
+notifier:
+  backends: ["log"]
+job:
+  start: "00:00"
+  end: "23:59"
+  sleepMinutes: 5
+`, fixture)
+	if err := ioutil.WriteFile(yamlLocation, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return yamlLocation
+}
+
+func TestRunCommandFlags(t *testing.T) {
+	useCommandFlags(t)
+	yamlLocation := writeCommandConfiguration(t)
+
+	if code := runCommand([]string{"--config", yamlLocation, "--log-unredacted", "validate-config"}); code != 0 {
+		t.Fatalf("expected the exit code 0, got %v", code)
+	}
+	if configurationLocation != yamlLocation {
+		t.Errorf("expected --config to set the configuration file, got %v", configurationLocation)
+	}
+	if !logUnredacted {
+		t.Error("expected --log-unredacted to disable the masking of the log")
+	}
+}
+
+func TestRunCommandFlagsAfterTheCommand(t *testing.T) {
+	useCommandFlags(t)
+	yamlLocation := writeCommandConfiguration(t)
+
+	if code := runCommand([]string{"validate-config", "--config", yamlLocation}); code != 0 {
+		t.Fatalf("expected the exit code 0, got %v", code)
+	}
+	if configurationLocation != yamlLocation {
+		t.Errorf("expected the command to accept --config, got %v", configurationLocation)
+	}
+	if logUnredacted {
+		t.Error("expected the log to be masked without --log-unredacted")
+	}
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	yamlLocation := writeCommandConfiguration(t)
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := ioutil.WriteFile(invalid, []byte("dataSource: \"oracle\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"valid configuration", []string{"--config", yamlLocation, "validate-config"}, 0},
+		{"invalid configuration", []string{"--config", invalid, "validate-config"}, 1},
+		{"missing configuration", []string{"--config", missing, "validate-config"}, 1},
+		{"unknown flag", []string{"--verbose", "validate-config"}, 2},
+		{"unknown command flag", []string{"validate-config", "--verbose"}, 2},
+		{"unknown command", []string{"--config", yamlLocation, "status"}, 2},
+		{"version", []string{"version"}, 0},
+		{"check without once", []string{"--config", yamlLocation, "check"}, 2},
+		{"check invalid format", []string{"--config", yamlLocation, "check", "--once", "--format", "xml"}, 2},
+		{"check missing configuration", []string{"--config", missing, "check", "--once"}, 1},
+		{"test-notify without type", []string{"--config", yamlLocation, "test-notify"}, 2},
+		{"test-notify unknown type", []string{"--config", yamlLocation, "test-notify", "pager"}, 2},
+		{"test-notify missing configuration", []string{"--config", missing, "test-notify", "start"}, 1},
+		{"test-notify", []string{"--config", yamlLocation, "test-notify", "start"}, 0},
+		{"set-password missing configuration", []string{"--config", missing, "set-password"}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useCommandFlags(t)
+			useMemory(t, &datasource.Memory{}, time.Hour)
+
+			if code := runCommand(test.args); code != test.code {
+				t.Errorf("expected the exit code %v, got %v", test.code, code)
+			}
+		})
+	}
+}
+
+func TestCheckOnce(t *testing.T) {
+	useCommandFlags(t)
+	configurationLocation = writeCommandConfiguration(t)
+	memory := &datasource.Memory{IncidentsWithoutOwner: []datasource.Ticket{{Number: "100", Priority: "1", Team: "Support"}}}
+	recorder := useMemory(t, memory, time.Hour)
+
+	var output bytes.Buffer
+	if code := checkOnce([]string{"--once"}, &output); code != 0 {
+		t.Fatalf("expected the exit code 0, got %v", code)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) < 3 || lines[0] != "incidentsWithoutOwner: 1 item(s)" || lines[2] != "tasksWithoutOwner: nothing found" || !strings.HasPrefix(strings.TrimSpace(lines[1]), "100 ") {
+		t.Errorf("expected the item found to be printed, got %q", output.String())
+	}
+	if notifications := recorder.Notifications(); len(notifications) != 0 {
+		t.Errorf("expected the items not to be notified, got %+v", notifications)
+	}
+
+	// the items are printed again, since the check does not remember them
+	output.Reset()
+	if code := checkOnce([]string{"--once", "--format", "json"}, &output); code != 0 {
+		t.Fatalf("expected the exit code 0, got %v", code)
+	}
+	var results []checkResult
+	if err := json.Unmarshal(output.Bytes(), &results); err != nil {
+		t.Fatalf("expected the output to be json, got %q: %v", output.String(), err)
+	}
+	if len(results) == 0 || results[0].Check != "incidentsWithoutOwner" || len(results[0].Items) != 1 || !strings.HasPrefix(results[0].Items[0], "100 ") || results[0].Error != "" {
+		t.Errorf("expected the item found in the json, got %+v", results)
+	}
+}
+
+func TestCheckOnceError(t *testing.T) {
+	useCommandFlags(t)
+	configurationLocation = writeCommandConfiguration(t)
+	useMemory(t, &datasource.Memory{Err: fmt.Errorf("connection lost")}, time.Hour)
+
+	var output bytes.Buffer
+	if code := checkOnce([]string{"--once"}, &output); code != 1 {
+		t.Errorf("expected the exit code 1, got %v", code)
+	}
+	if !strings.Contains(output.String(), "error: connection lost") {
+		t.Errorf("expected the error to be printed, got %q", output.String())
+	}
+}
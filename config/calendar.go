@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout string = "2006-01-02"
+	timeLayout string = "15:04"
+
+	// maxDaysToSearch limits the search for the next working time, in case the calendar has no windows at all
+	maxDaysToSearch int = 366
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Calendar holds when cherwell should be checked. When no weekday is configured, cherwell is checked
+// from monday to friday between job.start and job.end.
+// Windows are given in the form of "hh:mm-hh:mm", both ends included.
+type Calendar struct {
+	Weekdays     map[string][]string
+	Holidays     []string
+	HolidaysFile string `yaml:"holidaysFile"`
+	Exceptions   []CalendarException
+
+	// fileHolidays holds the holidays read from the holidays file
+	fileHolidays []string
+}
+
+// CalendarException replaces the windows of a specific date. An exception without windows means that
+// cherwell is not checked on that date, even if it is a working day.
+type CalendarException struct {
+	Date    string
+	Windows []string
+}
+
+// window is a time range within a day, in minutes since midnight
+type window struct {
+	start int
+	end   int
+}
+
+func (w window) contains(minute int) bool {
+	return minute >= w.start && minute <= w.end
+}
+
+// parseWindow parses a window in the form of "hh:mm-hh:mm". Windows that end before they start cross midnight,
+// so they are split in two windows of the same day, as it was done by job.start and job.end.
+func parseWindow(value string) ([]window, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid window \"%v\". Should be in the form of hh:mm-hh:mm", value)
+	}
+
+	start, err := parseMinute(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid window \"%v\". Should be in the form of hh:mm-hh:mm", value)
+	}
+
+	end, err := parseMinute(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid window \"%v\". Should be in the form of hh:mm-hh:mm", value)
+	}
+
+	if start <= end {
+		return []window{{start, end}}, nil
+	}
+	return []window{{0, end}, {start, 24*60 - 1}}, nil
+}
+
+func parseMinute(value string) (int, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWindows(values []string) ([]window, error) {
+	var windows []window
+	for _, value := range values {
+		w, err := parseWindow(value)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w...)
+	}
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+	return windows, nil
+}
+
+// Validate validates calendar values
+func (c Calendar) Validate() string {
+	validationMessage := ""
+
+	names := make([]string, 0, len(c.Weekdays))
+	for name := range c.Weekdays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		windows := c.Weekdays[name]
+		if _, isPresent := weekdayNames[strings.ToLower(name)]; !isPresent {
+			validationMessage += fmt.Sprintf("calendar.weekdays has an unknown weekday \"%v\"\n", name)
+		}
+
+		if _, err := parseWindows(windows); err != nil {
+			validationMessage += fmt.Sprintf("calendar.weekdays.%v has an %v\n", name, err)
+		}
+	}
+
+	for _, holiday := range c.Holidays {
+		if _, err := time.Parse(dateLayout, holiday); err != nil {
+			validationMessage += fmt.Sprintf("calendar.holidays has an invalid date \"%v\". Should be in the form of yyyy-mm-dd\n", holiday)
+		}
+	}
+
+	for i, exception := range c.Exceptions {
+		if _, err := time.Parse(dateLayout, exception.Date); err != nil {
+			validationMessage += fmt.Sprintf("calendar.exceptions[%v].date is invalid. Should be in the form of yyyy-mm-dd, but got \"%v\"\n", i, exception.Date)
+		}
+
+		if _, err := parseWindows(exception.Windows); err != nil {
+			validationMessage += fmt.Sprintf("calendar.exceptions[%v].windows has an %v\n", i, err)
+		}
+	}
+
+	return validationMessage
+}
+
+// loadHolidaysFile reads the holidays of the holidays file, if there is one
+func (c *Calendar) loadHolidaysFile() error {
+	if c.HolidaysFile == "" {
+		return nil
+	}
+
+	holidays, err := readICalendarDates(c.HolidaysFile)
+	if err != nil {
+		return fmt.Errorf("Error reading calendar.holidaysFile \"%v\". %v", c.HolidaysFile, err)
+	}
+
+	c.fileHolidays = holidays
+	return nil
+}
+
+// readICalendarDates returns the start dates of the events of an iCalendar (.ics) file, in the form of yyyy-mm-dd.
+// Only the date is considered, so events are treated as full day holidays.
+func readICalendarDates(fileLocation string) ([]string, error) {
+	file, err := os.Open(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dates []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "DTSTART") {
+			continue
+		}
+
+		// the value comes after the property parameters, such as in "DTSTART;VALUE=DATE:20211225"
+		separator := strings.LastIndex(line, ":")
+		if separator == -1 || len(line) < separator+9 {
+			return nil, fmt.Errorf("invalid event start \"%v\"", line)
+		}
+
+		date, err := time.Parse("20060102", line[separator+1:separator+9])
+		if err != nil {
+			return nil, fmt.Errorf("invalid event start \"%v\"", line)
+		}
+		dates = append(dates, date.Format(dateLayout))
+	}
+
+	return dates, scanner.Err()
+}
+
+// isHoliday returns true if the date is one of the configured holidays
+func (c Calendar) isHoliday(date string) bool {
+	return contains(c.Holidays, date) || contains(c.fileHolidays, date)
+}
+
+// windowsOn returns the windows in which cherwell should be checked on the day of the given time.
+// Exceptions take precedence over holidays, which take precedence over the weekdays.
+func (c Calendar) windowsOn(day time.Time, job Job) []window {
+	date := day.Format(dateLayout)
+
+	for _, exception := range c.Exceptions {
+		if exception.Date == date {
+			windows, _ := parseWindows(exception.Windows)
+			return windows
+		}
+	}
+
+	if c.isHoliday(date) {
+		return nil
+	}
+
+	if len(c.Weekdays) == 0 {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			return nil
+		}
+		windows, _ := parseWindows([]string{job.Start + "-" + job.End})
+		return windows
+	}
+
+	for name, values := range c.Weekdays {
+		if weekdayNames[strings.ToLower(name)] == day.Weekday() {
+			windows, _ := parseWindows(values)
+			return windows
+		}
+	}
+	return nil
+}
+
+// IsWorkingTime returns true if cherwell should be checked at the given time, which is evaluated in the job's time zone.
+// When it returns false, the reason is also returned.
+func (c Calendar) IsWorkingTime(t time.Time, job Job) (bool, string) {
+	t = t.In(job.GetLocation())
+	date := t.Format(dateLayout)
+	windows := c.windowsOn(t, job)
+
+	if len(windows) == 0 {
+		if c.isHoliday(date) {
+			return false, "Current date is a holiday"
+		}
+		return false, "Current date is not a working day"
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range windows {
+		if w.contains(minute) {
+			return true, ""
+		}
+	}
+
+	return false, "Current time is not between valid work time range"
+}
+
+// NextWorkingTime returns the first time, from the given time on, in which cherwell should be checked, in the job's time zone.
+// The second value is false when there is no working time in the next year.
+// Days and windows are built from the wall clock, so a window keeps its hours across daylight saving time transitions.
+// A window that starts in the hour skipped by a transition starts right after it.
+func (c Calendar) NextWorkingTime(t time.Time, job Job) (time.Time, bool) {
+	t = t.In(job.GetLocation()).Truncate(time.Minute)
+
+	for day := 0; day < maxDaysToSearch; day++ {
+		current := time.Date(t.Year(), t.Month(), t.Day()+day, 0, 0, 0, 0, t.Location())
+		fromMinute := 0
+		if day == 0 {
+			fromMinute = t.Hour()*60 + t.Minute()
+		}
+
+		for _, w := range c.windowsOn(current, job) {
+			if w.end < fromMinute {
+				continue
+			}
+
+			minute := w.start
+			if minute < fromMinute {
+				minute = fromMinute
+			}
+			return wallClock(current, minute), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// wallClock returns the given minute of the day. When the minute is skipped by a daylight saving time transition,
+// the first minute after the transition is returned, since time.Date may normalize it to a time before the transition.
+func wallClock(day time.Time, minute int) time.Time {
+	t := time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, day.Location())
+	for t.Day() == day.Day() && t.Hour()*60+t.Minute() < minute {
+		t = t.Add(time.Minute)
+	}
+	return t
+}
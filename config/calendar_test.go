@@ -0,0 +1,324 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("the time zone database is not available. %v", err)
+	}
+	return location
+}
+
+// useLocalLocation replaces the machine's time zone for the duration of the test
+func useLocalLocation(t *testing.T, location *time.Location) {
+	previous := time.Local
+	time.Local = location
+	t.Cleanup(func() { time.Local = previous })
+}
+
+func TestIsWorkingTime(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	tokyo := loadLocation(t, "Asia/Tokyo")
+	useLocalLocation(t, loadLocation(t, "America/Sao_Paulo"))
+
+	newYorkJob := Job{Start: "08:00", End: "17:59", Timezone: "America/New_York"}
+	tokyoJob := Job{Start: "08:00", End: "17:59", Timezone: "Asia/Tokyo"}
+	localJob := Job{Start: "08:00", End: "17:59"}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		job      Job
+		time     time.Time
+		expected bool
+	}{
+		// on 2021-03-14 the clocks in New York jump from 02:00 to 03:00
+		{"spring forward, after the gap", Calendar{Weekdays: map[string][]string{"sunday": {"02:30-05:00"}}}, newYorkJob,
+			time.Date(2021, 3, 14, 3, 15, 0, 0, newYork), true},
+		{"spring forward, before the window", Calendar{Weekdays: map[string][]string{"sunday": {"02:30-05:00"}}}, newYorkJob,
+			time.Date(2021, 3, 14, 1, 59, 0, 0, newYork), false},
+		{"spring forward, window kept in wall clock", Calendar{Weekdays: map[string][]string{"sunday": {"08:00-09:00"}}}, newYorkJob,
+			time.Date(2021, 3, 14, 12, 30, 0, 0, time.UTC), true},
+		// on 2021-11-07 the clocks in New York go back from 02:00 to 01:00, so 01:15 happens twice
+		{"fall back, first 01:15", Calendar{Weekdays: map[string][]string{"sunday": {"01:00-01:30"}}}, newYorkJob,
+			time.Date(2021, 11, 7, 5, 15, 0, 0, time.UTC), true},
+		{"fall back, second 01:15", Calendar{Weekdays: map[string][]string{"sunday": {"01:00-01:30"}}}, newYorkJob,
+			time.Date(2021, 11, 7, 6, 15, 0, 0, time.UTC), true},
+		{"fall back, first 01:45", Calendar{Weekdays: map[string][]string{"sunday": {"01:00-01:30"}}}, newYorkJob,
+			time.Date(2021, 11, 7, 5, 45, 0, 0, time.UTC), false},
+		// a window that crosses midnight is split in two windows of the same day
+		{"midnight, before midnight", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 22, 23, 30, 0, 0, newYork), true},
+		{"midnight, early morning of the same day", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 22, 1, 30, 0, 0, newYork), true},
+		{"midnight, early morning of the next day", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 23, 1, 30, 0, 0, newYork), false},
+		{"midnight, between the windows", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 22, 12, 0, 0, 0, newYork), false},
+		{"midnight, job.start after job.end", Calendar{}, Job{Start: "22:00", End: "02:00", Timezone: "America/New_York"},
+			time.Date(2021, 1, 22, 23, 0, 0, 0, newYork), true},
+		// 2021-01-22 00:30 UTC is thursday 21:30 in São Paulo, but friday 09:30 in Tokyo
+		{"job.timezone, working in the job's zone", Calendar{}, tokyoJob, time.Date(2021, 1, 22, 0, 30, 0, 0, time.UTC), true},
+		{"job.timezone, working in the machine's zone only", Calendar{}, tokyoJob, time.Date(2021, 1, 21, 12, 0, 0, 0, time.UTC), false},
+		// 2021-01-23 00:30 UTC is friday 21:30 in São Paulo, but saturday in Tokyo
+		{"job.timezone, weekday of the job's zone", Calendar{}, tokyoJob, time.Date(2021, 1, 23, 0, 30, 0, 0, time.UTC), false},
+		{"job.timezone, holiday of the job's zone", Calendar{Holidays: []string{"2021-01-22"}}, tokyoJob,
+			time.Date(2021, 1, 22, 0, 30, 0, 0, time.UTC), false},
+		{"machine's zone when job.timezone is empty", Calendar{}, localJob, time.Date(2021, 1, 21, 12, 0, 0, 0, time.UTC), true},
+		// friday 09:30 in Tokyo is thursday 21:30 in São Paulo
+		{"machine's zone when job.timezone is empty, outside", Calendar{}, localJob, time.Date(2021, 1, 22, 9, 30, 0, 0, tokyo), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isWorkingTime, reason := test.calendar.IsWorkingTime(test.time, test.job)
+			if isWorkingTime != test.expected {
+				t.Errorf("expected %v at %v, got %v (%v)", test.expected, test.time.In(test.job.GetLocation()), isWorkingTime, reason)
+			}
+		})
+	}
+}
+
+func TestNextWorkingTime(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	tokyo := loadLocation(t, "Asia/Tokyo")
+	useLocalLocation(t, loadLocation(t, "America/Sao_Paulo"))
+
+	newYorkJob := Job{Start: "08:00", End: "17:59", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		job      Job
+		time     time.Time
+		expected time.Time
+	}{
+		{"spring forward, window starting in the gap starts after it", Calendar{Weekdays: map[string][]string{"sunday": {"02:30-05:00"}}}, newYorkJob,
+			time.Date(2021, 3, 14, 0, 0, 0, 0, newYork), time.Date(2021, 3, 14, 3, 0, 0, 0, newYork)},
+		{"spring forward, window after the gap keeps its hour", Calendar{Weekdays: map[string][]string{"sunday": {"08:00-09:00"}}}, newYorkJob,
+			time.Date(2021, 3, 14, 0, 0, 0, 0, newYork), time.Date(2021, 3, 14, 12, 0, 0, 0, time.UTC)},
+		{"fall back, first occurrence of the repeated hour", Calendar{Weekdays: map[string][]string{"sunday": {"01:00-01:30"}}}, newYorkJob,
+			time.Date(2021, 11, 7, 0, 30, 0, 0, newYork), time.Date(2021, 11, 7, 5, 0, 0, 0, time.UTC)},
+		{"fall back, window after the repeated hour keeps its hour", Calendar{Weekdays: map[string][]string{"sunday": {"08:00-09:00"}}}, newYorkJob,
+			time.Date(2021, 11, 7, 0, 30, 0, 0, newYork), time.Date(2021, 11, 7, 13, 0, 0, 0, time.UTC)},
+		{"within a window", Calendar{}, newYorkJob,
+			time.Date(2021, 1, 22, 10, 15, 30, 0, newYork), time.Date(2021, 1, 22, 10, 15, 0, 0, newYork)},
+		{"midnight, the same day's late window", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 22, 3, 0, 0, 0, newYork), time.Date(2021, 1, 22, 22, 0, 0, 0, newYork)},
+		{"midnight, the next week's early window", Calendar{Weekdays: map[string][]string{"friday": {"22:00-02:00"}}}, newYorkJob,
+			time.Date(2021, 1, 23, 0, 30, 0, 0, newYork), time.Date(2021, 1, 29, 0, 0, 0, 0, newYork)},
+		{"job.timezone, monday in the job's zone", Calendar{}, Job{Start: "08:00", End: "17:59", Timezone: "Asia/Tokyo"},
+			time.Date(2021, 1, 23, 12, 0, 0, 0, time.UTC), time.Date(2021, 1, 25, 8, 0, 0, 0, tokyo)},
+		{"holiday and exception", Calendar{Holidays: []string{"2021-01-25"}, Exceptions: []CalendarException{{Date: "2021-01-26", Windows: []string{"13:00-14:00"}}}},
+			newYorkJob, time.Date(2021, 1, 23, 12, 0, 0, 0, newYork), time.Date(2021, 1, 26, 13, 0, 0, 0, newYork)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, hasNext := test.calendar.NextWorkingTime(test.time, test.job)
+			if !hasNext || !next.Equal(test.expected) {
+				t.Errorf("expected %v, got %v (%v)", test.expected, next, hasNext)
+			}
+		})
+	}
+
+	if _, hasNext := (Calendar{Weekdays: map[string][]string{"monday": {}}}).NextWorkingTime(time.Now(), newYorkJob); hasNext {
+		t.Error("expected no working time in a calendar without windows")
+	}
+}
+
+func TestWallClock(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		day      time.Time
+		minute   int
+		expected time.Time
+	}{
+		{"regular day", time.Date(2021, 1, 22, 0, 0, 0, 0, newYork), 8 * 60, time.Date(2021, 1, 22, 8, 0, 0, 0, newYork)},
+		{"skipped minute", time.Date(2021, 3, 14, 0, 0, 0, 0, newYork), 2*60 + 30, time.Date(2021, 3, 14, 7, 0, 0, 0, time.UTC)},
+		{"first minute after the gap", time.Date(2021, 3, 14, 0, 0, 0, 0, newYork), 3 * 60, time.Date(2021, 3, 14, 7, 0, 0, 0, time.UTC)},
+		{"repeated minute", time.Date(2021, 11, 7, 0, 0, 0, 0, newYork), 60 + 30, time.Date(2021, 11, 7, 5, 30, 0, 0, time.UTC)},
+		{"last minute of the day", time.Date(2021, 3, 14, 0, 0, 0, 0, newYork), 24*60 - 1, time.Date(2021, 3, 14, 23, 59, 0, 0, newYork)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := wallClock(test.day, test.minute); !got.Equal(test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestJobTimezoneValidation(t *testing.T) {
+	loadLocation(t, "America/Sao_Paulo")
+
+	tests := []struct {
+		timezone string
+		valid    bool
+	}{
+		{"", true},
+		{"UTC", true},
+		{"America/Sao_Paulo", true},
+		{"Mars/Olympus_Mons", false},
+		{"america/sao paulo", false},
+		{"GMT-3", false},
+	}
+
+	for _, test := range tests {
+		job := Job{Start: "08:00", End: "17:59", SleepMinutes: 1, Timezone: test.timezone}
+		message := job.Validate(true)
+		if valid := !strings.Contains(message, "job.timezone is invalid"); valid != test.valid {
+			t.Errorf("%q: expected valid to be %v, got %q", test.timezone, test.valid, message)
+		}
+	}
+}
+
+func TestCalendarDays(t *testing.T) {
+	utcJob := Job{Start: "08:00", End: "17:59", Timezone: "UTC"}
+	weekdays := map[string][]string{"Monday": {"08:00-12:00", "13:00-18:00"}, "saturday": {"09:00-11:00"}}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		time     time.Time
+		expected bool
+		reason   string
+	}{
+		{"job range on a weekday", Calendar{}, time.Date(2021, 1, 25, 8, 0, 0, 0, time.UTC), true, ""},
+		{"job range, last minute included", Calendar{}, time.Date(2021, 1, 25, 17, 59, 59, 0, time.UTC), true, ""},
+		{"job range, after the end", Calendar{}, time.Date(2021, 1, 25, 18, 0, 0, 0, time.UTC), false, "Current time is not between valid work time range"},
+		{"job range on the weekend", Calendar{}, time.Date(2021, 1, 23, 10, 0, 0, 0, time.UTC), false, "Current date is not a working day"},
+		{"first window", Calendar{Weekdays: weekdays}, time.Date(2021, 1, 25, 11, 0, 0, 0, time.UTC), true, ""},
+		{"between windows", Calendar{Weekdays: weekdays}, time.Date(2021, 1, 25, 12, 30, 0, 0, time.UTC), false, "Current time is not between valid work time range"},
+		{"second window", Calendar{Weekdays: weekdays}, time.Date(2021, 1, 25, 18, 0, 0, 0, time.UTC), true, ""},
+		{"weekend window", Calendar{Weekdays: weekdays}, time.Date(2021, 1, 23, 10, 0, 0, 0, time.UTC), true, ""},
+		{"weekday without windows", Calendar{Weekdays: weekdays}, time.Date(2021, 1, 26, 10, 0, 0, 0, time.UTC), false, "Current date is not a working day"},
+		{"holiday", Calendar{Weekdays: weekdays, Holidays: []string{"2021-01-25"}}, time.Date(2021, 1, 25, 11, 0, 0, 0, time.UTC), false, "Current date is a holiday"},
+		{"holiday of the file", Calendar{fileHolidays: []string{"2021-01-25"}}, time.Date(2021, 1, 25, 11, 0, 0, 0, time.UTC), false, "Current date is a holiday"},
+		{"exception on a holiday", Calendar{Holidays: []string{"2021-01-25"}, Exceptions: []CalendarException{{Date: "2021-01-25", Windows: []string{"10:00-11:00"}}}},
+			time.Date(2021, 1, 25, 10, 30, 0, 0, time.UTC), true, ""},
+		{"exception replaces the windows", Calendar{Exceptions: []CalendarException{{Date: "2021-01-25", Windows: []string{"10:00-11:00"}}}},
+			time.Date(2021, 1, 25, 9, 0, 0, 0, time.UTC), false, "Current time is not between valid work time range"},
+		{"exception on the weekend", Calendar{Exceptions: []CalendarException{{Date: "2021-01-23", Windows: []string{"10:00-11:00"}}}},
+			time.Date(2021, 1, 23, 10, 0, 0, 0, time.UTC), true, ""},
+		{"exception without windows", Calendar{Exceptions: []CalendarException{{Date: "2021-01-25"}}},
+			time.Date(2021, 1, 25, 10, 0, 0, 0, time.UTC), false, "Current date is not a working day"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isWorkingTime, reason := test.calendar.IsWorkingTime(test.time, utcJob)
+			if isWorkingTime != test.expected || reason != test.reason {
+				t.Errorf("expected %v %q, got %v %q", test.expected, test.reason, isWorkingTime, reason)
+			}
+		})
+	}
+}
+
+func TestNextWorkingTimeSkipsHolidays(t *testing.T) {
+	utcJob := Job{Start: "08:00", End: "17:59", Timezone: "UTC"}
+	calendar := Calendar{Holidays: []string{"2021-01-25", "2021-01-26"}}
+
+	next, hasNext := calendar.NextWorkingTime(time.Date(2021, 1, 22, 18, 0, 0, 0, time.UTC), utcJob)
+	if expected := time.Date(2021, 1, 27, 8, 0, 0, 0, time.UTC); !hasNext || !next.Equal(expected) {
+		t.Errorf("expected the friday evening to be followed by %v, got %v (%v)", expected, next, hasNext)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []window
+	}{
+		{"08:00-17:59", []window{{8 * 60, 17*60 + 59}}},
+		{" 08:00 - 09:00 ", []window{{8 * 60, 9 * 60}}},
+		{"22:00-02:00", []window{{0, 2 * 60}, {22 * 60, 24*60 - 1}}},
+	}
+
+	for _, test := range tests {
+		windows, err := parseWindow(test.value)
+		if err != nil || !reflect.DeepEqual(windows, test.expected) {
+			t.Errorf("expected %q to be parsed as %v, got %v %v", test.value, test.expected, windows, err)
+		}
+	}
+
+	for _, value := range []string{"", "08:00", "08:00-", "8h-9h", "08:00-25:00", "08:00-09:00-10:00"} {
+		if _, err := parseWindow(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestLoadHolidaysFile(t *testing.T) {
+	dir := t.TempDir()
+	holidaysFile := filepath.Join(dir, "holidays.ics")
+	content := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20211225\r\nSUMMARY:Natal\r\nEND:VEVENT\r\nBEGIN:VEVENT\r\nDTSTART:20211102T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if err := ioutil.WriteFile(holidaysFile, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	calendar := Calendar{HolidaysFile: holidaysFile}
+	if err := calendar.loadHolidaysFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"2021-12-25", "2021-11-02"}; !reflect.DeepEqual(calendar.fileHolidays, expected) {
+		t.Errorf("expected the holidays %v, got %v", expected, calendar.fileHolidays)
+	}
+
+	invalidFile := filepath.Join(dir, "invalid.ics")
+	if err := ioutil.WriteFile(invalidFile, []byte("BEGIN:VEVENT\nDTSTART:2021\nEND:VEVENT\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, location := range []string{invalidFile, filepath.Join(dir, "missing.ics")} {
+		if err := (&Calendar{HolidaysFile: location}).loadHolidaysFile(); err == nil {
+			t.Errorf("expected %v to be rejected", location)
+		}
+	}
+}
+
+func TestCalendarValidate(t *testing.T) {
+	valid := Calendar{
+		Weekdays:   map[string][]string{"Monday": {"08:00-12:00", "22:00-02:00"}},
+		Holidays:   []string{"2021-12-25"},
+		Exceptions: []CalendarException{{Date: "2021-12-24", Windows: []string{"08:00-12:00"}}, {Date: "2021-12-31"}},
+	}
+	if message := valid.Validate(); message != "" {
+		t.Errorf("expected the calendar to be valid, got %q", message)
+	}
+
+	invalid := Calendar{
+		Weekdays:   map[string][]string{"tuesday": {"8h-12h"}, "funday": {"08:00-12:00"}, "monday": {"08:00"}},
+		Holidays:   []string{"25/12/2021"},
+		Exceptions: []CalendarException{{Date: "2021-13-01", Windows: []string{"08:00-12"}}},
+	}
+	expected := []string{
+		"calendar.weekdays has an unknown weekday \"funday\"",
+		"calendar.weekdays.monday has an invalid window \"08:00\"",
+		"calendar.weekdays.tuesday has an invalid window \"8h-12h\"",
+		"calendar.holidays has an invalid date \"25/12/2021\"",
+		"calendar.exceptions[0].date is invalid",
+		"calendar.exceptions[0].windows has an invalid window \"08:00-12\"",
+	}
+
+	lines := strings.Split(strings.TrimSpace(invalid.Validate()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v messages, got %q", len(expected), lines)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("expected the message %v to start with %q, got %q", i, expected[i], line)
+		}
+	}
+}
@@ -2,8 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"os"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v2"
 )
 
@@ -13,13 +19,98 @@ var timeRegex *regexp.Regexp = regexp.MustCompile(`(?m)\d\d:\d\d`)
 type Configuration struct {
 	User         User
 	Notification Notification
+	Profiles     []Profile
+	Notifier     Notifier
 	Job          Job
+	Schedules    Schedules
+	Calendar     Calendar
+	DataSource   string `yaml:"dataSource"`
 	Database     Database
+	Schema       Schema
+	Cherwell     Cherwell
+	Fixture      string
+	SLA          SLA `yaml:"sla"`
+	Portal       Portal
+	Rules        []Rule
+	Log          Log
+	Assets       Assets
+}
+
+const (
+	// SQLDataSource reads the items directly from the cherwell SQL Server database
+	SQLDataSource string = "sql"
+	// RESTDataSource reads the items through the cherwell REST API
+	RESTDataSource string = "rest"
+	// FixtureDataSource reads fixed items from the YAML file given in fixture, to try the notifications without cherwell
+	FixtureDataSource string = "fixture"
+)
+
+// GetDataSource returns the configured data source, falling back to the SQL Server database when none is given
+func (c Configuration) GetDataSource() string {
+	if c.DataSource == "" {
+		return SQLDataSource
+	}
+	return c.DataSource
+}
+
+// Secrets returns the passwords and tokens given in the configuration, which must not be written to the log
+func (c Configuration) Secrets() []string {
+	secrets := []string{c.Database.Password, c.Cherwell.Password, c.Notifier.Email.Password}
+	for _, value := range c.Notifier.Webhook.Headers {
+		secrets = append(secrets, value)
+	}
+	return secrets
+}
+
+// PersonalData returns the names and e-mails of the users of the configuration, which are masked in the log
+func (c Configuration) PersonalData() []string {
+	data := []string{c.User.Name, c.User.Email}
+	for _, profile := range c.Profiles {
+		data = append(data, profile.User.Name, profile.User.Email)
+	}
+	return data
 }
 
 // Validate validates configuration values
 func (c Configuration) Validate() error {
-	validationMessage := c.Job.Validate() + c.Database.Validate()
+	validationMessage := c.Notifier.Validate() + c.Job.Validate(len(c.Calendar.Weekdays) == 0) + c.Schedules.Validate() + c.Calendar.Validate() + c.SLA.Validate() + c.Portal.Validate() + c.Log.Validate() + c.Assets.Validate()
+
+	switch c.GetDataSource() {
+	case SQLDataSource:
+		validationMessage += c.Database.Validate() + c.Schema.Validate()
+	case RESTDataSource:
+		validationMessage += c.Cherwell.Validate(c.enabledNotifications())
+		if len(c.Rules) > 0 {
+			validationMessage += fmt.Sprintln("rules are only supported when dataSource is \"sql\" or \"fixture\"")
+		}
+	case FixtureDataSource:
+		if c.Fixture == "" {
+			validationMessage += fmt.Sprintln("fixture cannot be empty when dataSource is \"fixture\"")
+		} else if _, err := os.Stat(c.Fixture); err != nil {
+			validationMessage += fmt.Sprintf("fixture \"%v\" cannot be read. %v\n", c.Fixture, err)
+		}
+	default:
+		validationMessage += fmt.Sprintf("dataSource is invalid. Should be \"%v\", \"%v\" or \"%v\", but got \"%v\"\n", SQLDataSource, RESTDataSource, FixtureDataSource, c.DataSource)
+	}
+
+	profileNames := make(map[string]bool)
+	for i, profile := range c.Profiles {
+		validationMessage += profile.Validate(i)
+		if profileNames[profile.GetName()] {
+			validationMessage += fmt.Sprintf("profiles[%v].name \"%v\" is used by more than one profile\n", i, profile.GetName())
+		}
+		profileNames[profile.GetName()] = true
+	}
+
+	ruleNames := make(map[string]bool)
+	for i, rule := range c.Rules {
+		validationMessage += rule.Validate(i)
+		if rule.Name != "" && ruleNames[rule.Name] {
+			validationMessage += fmt.Sprintf("rules[%v].name \"%v\" is used by more than one rule\n", i, rule.Name)
+		}
+		ruleNames[rule.Name] = true
+	}
+
 	if validationMessage != "" {
 		return fmt.Errorf("Error in the config file.\n" + validationMessage)
 	}
@@ -27,6 +118,76 @@ func (c Configuration) Validate() error {
 	return nil
 }
 
+// IsNotificationsEnabled returns true if there is at least one built-in notification enabled or one rule configured
+func (c Configuration) IsNotificationsEnabled() bool {
+	return c.enabledNotifications().IsNotificationsEnabled() || len(c.Rules) > 0
+}
+
+// GetProfiles returns the profiles whose notifications are checked. When no profile is configured,
+// a single unnamed profile is made of the user and notification sections.
+func (c Configuration) GetProfiles() []Profile {
+	if len(c.Profiles) == 0 {
+		return []Profile{{User: c.User, Notification: c.Notification, implicit: true}}
+	}
+	return c.Profiles
+}
+
+// enabledNotifications returns the notifications that are enabled in at least one profile
+func (c Configuration) enabledNotifications() Notification {
+	var enabled Notification
+	for _, profile := range c.GetProfiles() {
+		enabled.EnableIncidentsWithoutOwnerNotification = enabled.EnableIncidentsWithoutOwnerNotification || profile.Notification.EnableIncidentsWithoutOwnerNotification
+		enabled.EnableTasksWithoutOwnerNotification = enabled.EnableTasksWithoutOwnerNotification || profile.Notification.EnableTasksWithoutOwnerNotification
+		enabled.EnableIncidentsWithClosedTasksNotification = enabled.EnableIncidentsWithClosedTasksNotification || profile.Notification.EnableIncidentsWithClosedTasksNotification
+		enabled.EnableChangesThatNeedToBeValidatedNotification = enabled.EnableChangesThatNeedToBeValidatedNotification || profile.Notification.EnableChangesThatNeedToBeValidatedNotification
+		enabled.EnableChangesThatRequireUpdateNotification = enabled.EnableChangesThatRequireUpdateNotification || profile.Notification.EnableChangesThatRequireUpdateNotification
+	}
+	return enabled
+}
+
+// Profile is a team, with the identity of the user within it, whose notifications are checked.
+// Several profiles allow a single instance to watch more than one team.
+type Profile struct {
+	Name         string
+	User         User
+	Notification Notification
+
+	// implicit is true for the profile made of the user and notification sections, which has no name
+	implicit bool
+}
+
+// GetName returns the name that identifies the profile in the notifications, falling back to the team.
+// It is empty when no profile is configured.
+func (p Profile) GetName() string {
+	if p.implicit {
+		return ""
+	}
+	if p.Name == "" {
+		return p.User.Team
+	}
+	return p.Name
+}
+
+// Kind returns the notification type prefixed by the profile's name, so that each profile keeps its own notified items.
+// The unnamed profile, used when no profile is configured, keeps the notification type as is.
+func (p Profile) Kind(notificationType string) string {
+	if p.GetName() == "" {
+		return notificationType
+	}
+	return p.GetName() + "/" + notificationType
+}
+
+// Validate validates profile values. The index is used to identify the profile in the messages
+func (p Profile) Validate(index int) string {
+	validationMessage := ""
+
+	if p.User.Team == "" {
+		validationMessage += fmt.Sprintf("profiles[%v].user.team cannot be empty\n", index)
+	}
+
+	return validationMessage
+}
+
 // User holds the user's configuration
 type User struct {
 	Name  string
@@ -102,22 +263,213 @@ func (notification *Notification) UnmarshalYAML(unmarshal func(interface{}) erro
 	return nil
 }
 
+// Notifier holds the configuration of how the notifications are emitted
+type Notifier struct {
+	Backends []string
+	Webhook  Webhook
+	Email    Email
+}
+
+// Webhook holds the configuration of the webhook backend, which posts the notifications as JSON to an url
+type Webhook struct {
+	URL            string
+	Headers        map[string]string
+	TimeoutSeconds int `yaml:"timeoutSeconds"`
+}
+
+// defaultWebhookTimeoutSeconds is used when notifier.webhook.timeoutSeconds is not given
+const defaultWebhookTimeoutSeconds int = 10
+
+// GetTimeout returns how long to wait for the webhook to respond
+func (w Webhook) GetTimeout() time.Duration {
+	if w.TimeoutSeconds == 0 {
+		return time.Duration(defaultWebhookTimeoutSeconds) * time.Second
+	}
+	return time.Duration(w.TimeoutSeconds) * time.Second
+}
+
+// Validate validates webhook values
+func (w Webhook) Validate() string {
+	validationMessage := ""
+
+	if parsedURL, err := url.Parse(w.URL); err != nil || parsedURL.Host == "" || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		validationMessage += fmt.Sprintf("notifier.webhook.url is invalid. Should be an http or https url, but got \"%v\"\n", w.URL)
+	}
+
+	if w.TimeoutSeconds < 0 {
+		validationMessage += fmt.Sprintln("notifier.webhook.timeoutSeconds cannot be negative")
+	}
+
+	return validationMessage
+}
+
+// Email holds the configuration of the email backend, which sends the notifications through an SMTP server
+type Email struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string
+	To       []string
+}
+
+// Validate validates email values
+func (e Email) Validate() string {
+	validationMessage := ""
+
+	if e.Host == "" {
+		validationMessage += fmt.Sprintln("notifier.email.host cannot be empty")
+	}
+
+	if e.Port <= 0 {
+		validationMessage += fmt.Sprintln("notifier.email.port is invalid. Should be greater than 0")
+	}
+
+	if e.From == "" {
+		validationMessage += fmt.Sprintln("notifier.email.from cannot be empty")
+	}
+
+	if len(e.To) == 0 {
+		validationMessage += fmt.Sprintln("notifier.email.to cannot be empty")
+	}
+
+	return validationMessage
+}
+
+// knownBackends are the notification backends supported by the notifier package
+var knownBackends = []string{"toast", "log", "dbus", "webhook", "email"}
+
+// GetBackends returns the configured backends, falling back to "toast" when none is given
+func (n Notifier) GetBackends() []string {
+	if len(n.Backends) == 0 {
+		return []string{"toast"}
+	}
+	return n.Backends
+}
+
+// Validate validates notifier values
+func (n Notifier) Validate() string {
+	validationMessage := ""
+
+	for _, backend := range n.Backends {
+		if !contains(knownBackends, backend) {
+			validationMessage += fmt.Sprintf("notifier.backends has an unknown backend \"%v\". Should be one of %v\n", backend, knownBackends)
+		}
+	}
+
+	if contains(n.Backends, "webhook") {
+		validationMessage += n.Webhook.Validate()
+	}
+
+	if contains(n.Backends, "email") {
+		validationMessage += n.Email.Validate()
+	}
+
+	return validationMessage
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Assets holds the configuration of the icons and images, which are embedded in the program
+type Assets struct {
+	// Directory holds files that replace the embedded icons and images with the same name, such as cherwell.png
+	Directory string
+}
+
+// Validate validates assets values
+func (a Assets) Validate() string {
+	if a.Directory == "" {
+		return ""
+	}
+
+	if fileInfo, err := os.Stat(a.Directory); err != nil || !fileInfo.IsDir() {
+		return fmt.Sprintf("assets.directory \"%v\" is not a directory\n", a.Directory)
+	}
+	return ""
+}
+
 // Job holds the job's configuration
 type Job struct {
-	Start        string
-	End          string
-	SleepMinutes int `yaml:"sleepMinutes"`
+	Start             string
+	End               string
+	SleepMinutes      int `yaml:"sleepMinutes"`
+	EscalationMinutes int `yaml:"escalationMinutes"`
+	Retries           int
+	OutageMinutes     int `yaml:"outageMinutes"`
+	Timezone          string
 }
 
-// Validate validates database values
-func (j Job) Validate() string {
+const (
+	// defaultEscalationMinutes is used when job.escalationMinutes is not given
+	defaultEscalationMinutes int = 15
+	// defaultRetries is used when job.retries is not given
+	defaultRetries int = 3
+	// defaultOutageMinutes is used when job.outageMinutes is not given
+	defaultOutageMinutes int = 5
+)
+
+// GetEscalationInterval returns how long to wait before notifying again an item that was already notified
+func (j Job) GetEscalationInterval() time.Duration {
+	if j.EscalationMinutes == 0 {
+		return time.Duration(defaultEscalationMinutes) * time.Minute
+	}
+	return time.Duration(j.EscalationMinutes) * time.Minute
+}
+
+// GetRetries returns how many times a failed check of cherwell is retried before it is considered an outage
+func (j Job) GetRetries() int {
+	if j.Retries == 0 {
+		return defaultRetries
+	}
+	return j.Retries
+}
+
+// GetOutageThreshold returns for how long cherwell must be unreachable before the user is notified
+func (j Job) GetOutageThreshold() time.Duration {
+	if j.OutageMinutes == 0 {
+		return time.Duration(defaultOutageMinutes) * time.Minute
+	}
+	return time.Duration(j.OutageMinutes) * time.Minute
+}
+
+// GetLocation returns the time zone in which the calendar and the schedules are evaluated,
+// falling back to the machine's time zone when job.timezone is not given
+func (j Job) GetLocation() *time.Location {
+	if j.Timezone == "" {
+		return time.Local
+	}
+
+	location, err := time.LoadLocation(j.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// GetSchedule returns the given schedule, falling back to an interval of job.sleepMinutes when it is empty
+func (j Job) GetSchedule(schedule string) string {
+	if schedule == "" {
+		return intervalSchedule(j.SleepMinutes)
+	}
+	return schedule
+}
+
+// Validate validates job values. The time range is only required when there are no weekdays in the calendar
+func (j Job) Validate(requireTimeRange bool) string {
 	validationMessage := ""
 
-	if !IsValidTime(j.Start) {
+	if requireTimeRange && !IsValidTime(j.Start) {
 		validationMessage += fmt.Sprintf("job.start is invalid. Should be in the form of hh:mm, but got \"%v\"\n", j.Start)
 	}
 
-	if !IsValidTime(j.End) {
+	if requireTimeRange && !IsValidTime(j.End) {
 		validationMessage += fmt.Sprintf("job.end is invalid. Should be in the form of hh:mm, but got \"%v\"\n", j.End)
 	}
 
@@ -125,6 +477,22 @@ func (j Job) Validate() string {
 		validationMessage += fmt.Sprintln("job.sleepMinutes cannot be 0")
 	}
 
+	if j.EscalationMinutes < 0 {
+		validationMessage += fmt.Sprintln("job.escalationMinutes cannot be negative")
+	}
+
+	if j.Retries < 0 {
+		validationMessage += fmt.Sprintln("job.retries cannot be negative")
+	}
+
+	if j.OutageMinutes < 0 {
+		validationMessage += fmt.Sprintln("job.outageMinutes cannot be negative")
+	}
+
+	if _, err := time.LoadLocation(j.Timezone); err != nil {
+		validationMessage += fmt.Sprintf("job.timezone is invalid. Should be an IANA time zone name, such as \"America/Sao_Paulo\", but got \"%v\"\n", j.Timezone)
+	}
+
 	return validationMessage
 }
 
@@ -136,6 +504,198 @@ func IsValidTime(time string) bool {
 	return false
 }
 
+// Schedules holds when each notification is checked. A schedule is either a cron expression, such as "*/30 8-18 * * 1-5",
+// or an interval, such as "@every 5m". The notifications without a schedule are checked every job.sleepMinutes.
+// The calendar still applies, so a scheduled check outside of the working time is skipped.
+type Schedules struct {
+	IncidentsWithoutOwner        string `yaml:"incidentsWithoutOwner"`
+	TasksWithoutOwner            string `yaml:"tasksWithoutOwner"`
+	IncidentsWithClosedTasks     string `yaml:"incidentsWithClosedTasks"`
+	ChangesThatNeedToBeValidated string `yaml:"changesThatNeedToBeValidated"`
+	ChangesThatRequireUpdate     string `yaml:"changesThatRequireUpdate"`
+}
+
+// Validate validates schedules values
+func (s Schedules) Validate() string {
+	validationMessage := ""
+
+	schedules := map[string]string{
+		"incidentsWithoutOwner":        s.IncidentsWithoutOwner,
+		"tasksWithoutOwner":            s.TasksWithoutOwner,
+		"incidentsWithClosedTasks":     s.IncidentsWithClosedTasks,
+		"changesThatNeedToBeValidated": s.ChangesThatNeedToBeValidated,
+		"changesThatRequireUpdate":     s.ChangesThatRequireUpdate,
+	}
+	names := make([]string, 0, len(schedules))
+	for name := range schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		schedule := schedules[name]
+		if schedule == "" {
+			continue
+		}
+		if _, err := ParseSchedule(schedule, time.Local); err != nil {
+			validationMessage += fmt.Sprintf("schedules.%v \"%v\" is invalid. %v\n", name, schedule, err)
+		}
+	}
+
+	return validationMessage
+}
+
+// ParseSchedule parses a schedule, which is either a standard cron expression (minute, hour, day of month, month and day of week),
+// a descriptor such as "@hourly" or an interval such as "@every 5m". Cron expressions are evaluated in the given location,
+// unless the expression sets its own with the "CRON_TZ=" prefix.
+func ParseSchedule(schedule string, location *time.Location) (cron.Schedule, error) {
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec, ok := parsed.(*cron.SpecSchedule); ok && !strings.HasPrefix(schedule, "CRON_TZ=") && !strings.HasPrefix(schedule, "TZ=") {
+		spec.Location = location
+	}
+	return parsed, nil
+}
+
+func intervalSchedule(minutes int) string {
+	return fmt.Sprintf("@every %vm", minutes)
+}
+
+// SLA holds the thresholds, by incident priority, in which the notifications are escalated as the SLA deadline approaches
+type SLA struct {
+	Thresholds map[int]SLAThreshold
+}
+
+// SLAThreshold holds the percentages of elapsed SLA time from which a ticket is escalated to normal and urgent.
+// Once the deadline is breached the ticket is always escalated to critical.
+type SLAThreshold struct {
+	Normal int
+	Urgent int
+}
+
+// defaultSLAThreshold is used for the priorities without a configured threshold
+var defaultSLAThreshold = SLAThreshold{Normal: 50, Urgent: 80}
+
+// GetThreshold returns the threshold of the given priority, falling back to the default threshold
+func (s SLA) GetThreshold(priority int) SLAThreshold {
+	if threshold, isPresent := s.Thresholds[priority]; isPresent {
+		return threshold
+	}
+	return defaultSLAThreshold
+}
+
+// Validate validates sla values
+func (s SLA) Validate() string {
+	validationMessage := ""
+
+	for priority, threshold := range s.Thresholds {
+		if threshold.Normal <= 0 || threshold.Normal > 100 {
+			validationMessage += fmt.Sprintf("sla.thresholds.%v.normal should be between 1 and 100, but got %v\n", priority, threshold.Normal)
+		}
+
+		if threshold.Urgent <= 0 || threshold.Urgent > 100 {
+			validationMessage += fmt.Sprintf("sla.thresholds.%v.urgent should be between 1 and 100, but got %v\n", priority, threshold.Urgent)
+		}
+
+		if threshold.Normal > threshold.Urgent {
+			validationMessage += fmt.Sprintf("sla.thresholds.%v.normal cannot be greater than sla.thresholds.%v.urgent\n", priority, priority)
+		}
+	}
+
+	return validationMessage
+}
+
+// ticketNumberPlaceholder is replaced by the ticket's number in the portal url templates
+const ticketNumberPlaceholder string = "{{number}}"
+
+// Portal holds the url templates used to open the tickets in the cherwell web client,
+// such as "https://cherwell/CherwellClient/Access/incident/{{number}}"
+type Portal struct {
+	IncidentURL string `yaml:"incidentUrl"`
+	ChangeURL   string `yaml:"changeUrl"`
+}
+
+// Validate validates portal values
+func (p Portal) Validate() string {
+	validationMessage := ""
+
+	if p.IncidentURL != "" && !strings.Contains(p.IncidentURL, ticketNumberPlaceholder) {
+		validationMessage += fmt.Sprintf("portal.incidentUrl should contain %v, but got \"%v\"\n", ticketNumberPlaceholder, p.IncidentURL)
+	}
+
+	if p.ChangeURL != "" && !strings.Contains(p.ChangeURL, ticketNumberPlaceholder) {
+		validationMessage += fmt.Sprintf("portal.changeUrl should contain %v, but got \"%v\"\n", ticketNumberPlaceholder, p.ChangeURL)
+	}
+
+	return validationMessage
+}
+
+// TicketURL returns the url of the ticket, replacing the placeholder of the template by the ticket's number
+func TicketURL(template string, number string) string {
+	return strings.Replace(template, ticketNumberPlaceholder, url.PathEscape(number), -1)
+}
+
+// Rule is a user defined notification. Its query is executed against the cherwell database and
+// the values of the given column are notified. The query may use the parameters :team, :email and :userName,
+// which are bound from the user's configuration.
+type Rule struct {
+	Name         string
+	Query        string
+	Column       string
+	Title        string
+	Message      string
+	SleepMinutes int `yaml:"sleepMinutes"`
+	Schedule     string
+}
+
+// Validate validates rule values. The index is used to identify the rule in the messages
+func (r Rule) Validate(index int) string {
+	validationMessage := ""
+
+	if r.Name == "" {
+		validationMessage += fmt.Sprintf("rules[%v].name cannot be empty\n", index)
+	}
+
+	if r.Query == "" {
+		validationMessage += fmt.Sprintf("rules[%v].query cannot be empty\n", index)
+	}
+
+	if r.Column == "" {
+		validationMessage += fmt.Sprintf("rules[%v].column cannot be empty\n", index)
+	}
+
+	if r.Title == "" {
+		validationMessage += fmt.Sprintf("rules[%v].title cannot be empty\n", index)
+	}
+
+	if r.SleepMinutes < 0 {
+		validationMessage += fmt.Sprintf("rules[%v].sleepMinutes cannot be negative\n", index)
+	}
+
+	if r.Schedule != "" {
+		if _, err := ParseSchedule(r.Schedule, time.Local); err != nil {
+			validationMessage += fmt.Sprintf("rules[%v].schedule \"%v\" is invalid. %v\n", index, r.Schedule, err)
+		}
+	}
+
+	return validationMessage
+}
+
+// GetSchedule returns when the rule should be checked. The schedule takes precedence over sleepMinutes and,
+// when neither is given, the rule is checked every job.sleepMinutes
+func (r Rule) GetSchedule(job Job) string {
+	if r.Schedule != "" {
+		return r.Schedule
+	}
+	if r.SleepMinutes > 0 {
+		return intervalSchedule(r.SleepMinutes)
+	}
+	return job.GetSchedule("")
+}
+
 // Database holds the database's configuration
 type Database struct {
 	Server       string
@@ -143,12 +703,77 @@ type Database struct {
 	User         string
 	Password     string
 	DatabaseName string `yaml:"databaseName"`
+	// PasswordEnv is the name of an environment variable with the password, instead of giving it in the configuration
+	PasswordEnv string `yaml:"passwordEnv"`
+	// PasswordFile is the path of a file with the password
+	PasswordFile string `yaml:"passwordFile"`
+	// PasswordStore reads the password from the operating system's secret store, where it is saved by the set-password command
+	PasswordStore bool `yaml:"passwordStore"`
+	// QueryTimeoutSeconds limits how long a query may run before it is cancelled
+	QueryTimeoutSeconds int `yaml:"queryTimeoutSeconds"`
+	// Instance is the named instance of the server. Without a port, its port is resolved by the SQL Server Browser
+	Instance string
+	// Encrypt is "true" to encrypt the whole connection, "false" to encrypt only the login or "disable" to not encrypt at all
+	Encrypt string
+	// TrustServerCertificate accepts any certificate of the server, without verifying it
+	TrustServerCertificate bool `yaml:"trustServerCertificate"`
+	// Certificate is the path of the CA certificate that signs the server certificate, instead of the system's CAs
+	Certificate string
+	// HostNameInCertificate is the host name in the server certificate, when it is not the server
+	HostNameInCertificate string `yaml:"hostNameInCertificate"`
+	// AppName identifies the program in the server, such as in sp_who
+	AppName string `yaml:"appName"`
+	// ConnectionTimeoutSeconds limits how long connecting and logging in may take
+	ConnectionTimeoutSeconds int `yaml:"connectionTimeoutSeconds"`
+	// DialTimeoutSeconds limits how long opening the network connection may take
+	DialTimeoutSeconds int `yaml:"dialTimeoutSeconds"`
+	// FailoverPartner is the server used when the server is not available, in a database mirroring setup
+	FailoverPartner string `yaml:"failoverPartner"`
+	// FailoverPort is the port of the failover partner
+	FailoverPort int `yaml:"failoverPort"`
+	// Params are other parameters of the connection string of go-mssqldb, such as "packet size"
+	Params map[string]string
+}
+
+// defaultAppName identifies the program in the server when database.appName is not given
+const defaultAppName string = "cwnotifier"
+
+// knownEncryptValues are the values of the encrypt parameter of go-mssqldb
+var knownEncryptValues = []string{"true", "false", "disable"}
+
+// connectionParams are the parameters of go-mssqldb set by the other database fields, which cannot be given in database.params
+var connectionParams = []string{
+	"server", "port", "user id", "password", "database", "encrypt", "trustservercertificate", "certificate",
+	"hostnameincertificate", "app name", "connection timeout", "dial timeout", "failoverpartner", "failoverport",
+}
+
+// GetAppName returns the name of the program in the server, falling back to "cwnotifier" when none is given
+func (d Database) GetAppName() string {
+	if d.AppName == "" {
+		return defaultAppName
+	}
+	return d.AppName
+}
+
+// defaultQueryTimeoutSeconds is used when database.queryTimeoutSeconds is not given
+const defaultQueryTimeoutSeconds int = 30
+
+// GetQueryTimeout returns how long a query may run before it is cancelled
+func (d Database) GetQueryTimeout() time.Duration {
+	if d.QueryTimeoutSeconds == 0 {
+		return time.Duration(defaultQueryTimeoutSeconds) * time.Second
+	}
+	return time.Duration(d.QueryTimeoutSeconds) * time.Second
 }
 
 // Validate validates database values
 func (d Database) Validate() string {
 	validationMessage := ""
 
+	if d.QueryTimeoutSeconds < 0 {
+		validationMessage += fmt.Sprintln("database.queryTimeoutSeconds cannot be negative")
+	}
+
 	if d.Server == "" {
 		validationMessage += fmt.Sprintln("database.server cannot be empty")
 	}
@@ -157,14 +782,196 @@ func (d Database) Validate() string {
 		validationMessage += fmt.Sprintln("database.user cannot be empty")
 	}
 
-	if d.Password == "" {
-		validationMessage += fmt.Sprintln("database.password cannot be empty")
-	}
+	validationMessage += d.validatePassword()
 
 	if d.DatabaseName == "" {
 		validationMessage += fmt.Sprintln("database.databaseName cannot be empty")
 	}
 
+	validationMessage += d.validateConnection()
+
+	return validationMessage
+}
+
+// validateConnection validates the options of the connection, which are passed to go-mssqldb
+func (d Database) validateConnection() string {
+	validationMessage := ""
+
+	if d.Port < 0 || d.Port > 65535 {
+		validationMessage += fmt.Sprintf("database.port is invalid. Should be between 0 and 65535, but got %v\n", d.Port)
+	}
+
+	if d.Instance != "" && strings.Contains(d.Server, `\`) {
+		validationMessage += fmt.Sprintln("database.instance cannot be given when database.server already has the instance, such as \"server\\instance\"")
+	}
+
+	if strings.ContainsAny(d.Instance, `\/`) {
+		validationMessage += fmt.Sprintf("database.instance is invalid. Should be only the name of the instance, but got \"%v\"\n", d.Instance)
+	}
+
+	if d.Encrypt != "" && !contains(knownEncryptValues, strings.ToLower(d.Encrypt)) {
+		validationMessage += fmt.Sprintf("database.encrypt is invalid. Should be one of %v, but got \"%v\"\n", knownEncryptValues, d.Encrypt)
+	}
+
+	if d.Certificate != "" {
+		if fileInfo, err := os.Stat(d.Certificate); err != nil || fileInfo.IsDir() {
+			validationMessage += fmt.Sprintf("database.certificate is invalid. \"%v\" is not a file\n", d.Certificate)
+		}
+		if d.TrustServerCertificate {
+			validationMessage += fmt.Sprintln("database.certificate cannot be given when database.trustServerCertificate is true, since the certificate would not be verified")
+		}
+	}
+
+	if d.ConnectionTimeoutSeconds < 0 {
+		validationMessage += fmt.Sprintln("database.connectionTimeoutSeconds cannot be negative")
+	}
+
+	if d.DialTimeoutSeconds < 0 {
+		validationMessage += fmt.Sprintln("database.dialTimeoutSeconds cannot be negative")
+	}
+
+	if d.FailoverPort < 0 || d.FailoverPort > 65535 {
+		validationMessage += fmt.Sprintf("database.failoverPort is invalid. Should be between 0 and 65535, but got %v\n", d.FailoverPort)
+	}
+
+	if d.FailoverPort != 0 && d.FailoverPartner == "" {
+		validationMessage += fmt.Sprintln("database.failoverPort requires database.failoverPartner")
+	}
+
+	for _, name := range sortedKeys(d.Params) {
+		if strings.TrimSpace(name) == "" {
+			validationMessage += fmt.Sprintln("database.params cannot have an empty name")
+		} else if contains(connectionParams, strings.ToLower(strings.TrimSpace(name))) {
+			validationMessage += fmt.Sprintf("database.params cannot have \"%v\", it is set by the other database fields\n", name)
+		}
+	}
+
+	return validationMessage
+}
+
+// PasswordAccount returns the account under which the database password is saved in the secret store
+func (d Database) PasswordAccount() string {
+	return fmt.Sprintf("database:%v@%v/%v", d.User, d.Server, d.DatabaseName)
+}
+
+// validatePassword validates that exactly one source of the password is given
+func (d Database) validatePassword() string {
+	sources := 0
+	for _, given := range []bool{d.Password != "", d.PasswordEnv != "", d.PasswordFile != "", d.PasswordStore} {
+		if given {
+			sources++
+		}
+	}
+
+	if sources == 0 {
+		return fmt.Sprintln("database.password cannot be empty. Alternatively, give database.passwordEnv, database.passwordFile or database.passwordStore")
+	}
+	if sources > 1 {
+		return fmt.Sprintln("only one of database.password, database.passwordEnv, database.passwordFile and database.passwordStore can be given")
+	}
+
+	if d.PasswordEnv != "" {
+		if _, ok := os.LookupEnv(d.PasswordEnv); !ok {
+			return fmt.Sprintf("database.passwordEnv is invalid. The environment variable \"%v\" is not set\n", d.PasswordEnv)
+		}
+	}
+
+	if d.PasswordFile != "" {
+		if fileInfo, err := os.Stat(d.PasswordFile); err != nil || fileInfo.IsDir() {
+			return fmt.Sprintf("database.passwordFile is invalid. \"%v\" is not a file\n", d.PasswordFile)
+		}
+	}
+
+	return ""
+}
+
+// Cherwell holds the configuration of the cherwell REST API, used when dataSource is "rest"
+type Cherwell struct {
+	URL      string `yaml:"url"`
+	ClientID string `yaml:"clientId"`
+	User     string
+	Password string
+	AuthMode string `yaml:"authMode"`
+	Searches CherwellSearches
+}
+
+// CherwellSearches holds the saved searches that return the items of each notification
+type CherwellSearches struct {
+	IncidentsWithoutOwner        CherwellSearch `yaml:"incidentsWithoutOwner"`
+	TasksWithoutOwner            CherwellSearch `yaml:"tasksWithoutOwner"`
+	IncidentsWithClosedTasks     CherwellSearch `yaml:"incidentsWithClosedTasks"`
+	ChangesThatNeedToBeValidated CherwellSearch `yaml:"changesThatNeedToBeValidated"`
+	ChangesThatRequireUpdate     CherwellSearch `yaml:"changesThatRequireUpdate"`
+}
+
+// CherwellSearch identifies a cherwell saved search.
+// Field is the name of the field that holds the ticket's number, when empty the business object's public id is used.
+// Filters restrict the results to the ones whose field (the key) is equal to the value. The values
+// ":team", ":email" and ":userName" are replaced by the user's configuration.
+type CherwellSearch struct {
+	BusObID    string `yaml:"busObId"`
+	Name       string
+	Scope      string
+	ScopeOwner string `yaml:"scopeOwner"`
+	Field      string
+	Filters    map[string]string
+}
+
+// Validate validates cherwell values. Only the searches of the enabled notifications are required
+func (c Cherwell) Validate(notification Notification) string {
+	validationMessage := ""
+
+	if c.URL == "" {
+		validationMessage += fmt.Sprintln("cherwell.url cannot be empty")
+	}
+
+	if c.ClientID == "" {
+		validationMessage += fmt.Sprintln("cherwell.clientId cannot be empty")
+	}
+
+	if c.User == "" {
+		validationMessage += fmt.Sprintln("cherwell.user cannot be empty")
+	}
+
+	if c.Password == "" {
+		validationMessage += fmt.Sprintln("cherwell.password cannot be empty")
+	}
+
+	if notification.EnableIncidentsWithoutOwnerNotification {
+		validationMessage += c.Searches.IncidentsWithoutOwner.Validate("cherwell.searches.incidentsWithoutOwner")
+	}
+
+	if notification.EnableTasksWithoutOwnerNotification {
+		validationMessage += c.Searches.TasksWithoutOwner.Validate("cherwell.searches.tasksWithoutOwner")
+	}
+
+	if notification.EnableIncidentsWithClosedTasksNotification {
+		validationMessage += c.Searches.IncidentsWithClosedTasks.Validate("cherwell.searches.incidentsWithClosedTasks")
+	}
+
+	if notification.EnableChangesThatNeedToBeValidatedNotification {
+		validationMessage += c.Searches.ChangesThatNeedToBeValidated.Validate("cherwell.searches.changesThatNeedToBeValidated")
+	}
+
+	if notification.EnableChangesThatRequireUpdateNotification {
+		validationMessage += c.Searches.ChangesThatRequireUpdate.Validate("cherwell.searches.changesThatRequireUpdate")
+	}
+
+	return validationMessage
+}
+
+// Validate validates the saved search values. The path is used to identify the search in the messages
+func (s CherwellSearch) Validate(path string) string {
+	validationMessage := ""
+
+	if s.BusObID == "" {
+		validationMessage += fmt.Sprintf("%v.busObId cannot be empty\n", path)
+	}
+
+	if s.Name == "" {
+		validationMessage += fmt.Sprintf("%v.name cannot be empty\n", path)
+	}
+
 	return validationMessage
 }
 
@@ -176,11 +983,6 @@ func ReadConfiguration(yamlConfiguration []byte) (Configuration, error) {
 		return Configuration{}, err
 	}
 
-	err = configuration.Validate()
-	if err != nil {
-		return Configuration{}, err
-	}
-
 	if !configuration.Notification.gotMarshalled {
 		configuration.Notification.EnableIncidentsWithoutOwnerNotification = true
 		configuration.Notification.EnableTasksWithoutOwnerNotification = true
@@ -189,5 +991,22 @@ func ReadConfiguration(yamlConfiguration []byte) (Configuration, error) {
 		configuration.Notification.EnableChangesThatRequireUpdateNotification = true
 	}
 
+	// profiles without a notification section use the notifications of the configuration
+	for i := range configuration.Profiles {
+		if !configuration.Profiles[i].Notification.gotMarshalled {
+			configuration.Profiles[i].Notification = configuration.Notification
+		}
+	}
+
+	err = configuration.Calendar.loadHolidaysFile()
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	err = configuration.Validate()
+	if err != nil {
+		return Configuration{}, err
+	}
+
 	return configuration, err
 }
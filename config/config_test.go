@@ -0,0 +1,160 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTicketURL(t *testing.T) {
+	tests := []struct {
+		template string
+		number   string
+		expected string
+	}{
+		{"https://cherwell/CherwellClient/Access/incident/{{number}}", "123456", "https://cherwell/CherwellClient/Access/incident/123456"},
+		{"https://cherwell/CherwellClient/Access/ChangeRequest/{{number}}?edit=false", "7890", "https://cherwell/CherwellClient/Access/ChangeRequest/7890?edit=false"},
+		{"https://cherwell/{{number}}/{{number}}", "1", "https://cherwell/1/1"},
+		{"https://cherwell/incident/{{number}}", "12 34/5?x", "https://cherwell/incident/12%2034%2F5%3Fx"},
+	}
+
+	for _, test := range tests {
+		if url := TicketURL(test.template, test.number); url != test.expected {
+			t.Errorf("expected %v, got %v", test.expected, url)
+		}
+	}
+}
+
+func TestPortalValidate(t *testing.T) {
+	if message := (Portal{}).Validate(); message != "" {
+		t.Errorf("expected the empty portal to be valid, got %q", message)
+	}
+
+	valid := Portal{IncidentURL: "https://cherwell/incident/{{number}}", ChangeURL: "https://cherwell/change/{{number}}"}
+	if message := valid.Validate(); message != "" {
+		t.Errorf("expected %+v to be valid, got %q", valid, message)
+	}
+
+	message := (Portal{IncidentURL: "https://cherwell/incident", ChangeURL: "https://cherwell/change"}).Validate()
+	if !strings.Contains(message, "portal.incidentUrl") || !strings.Contains(message, "portal.changeUrl") {
+		t.Errorf("expected both templates without the placeholder to be rejected, got %q", message)
+	}
+}
+
+func TestSchedulesValidate(t *testing.T) {
+	if message := (Schedules{IncidentsWithoutOwner: "*/5 8-18 * * 1-5", TasksWithoutOwner: "@every 10m"}).Validate(); message != "" {
+		t.Errorf("expected the schedules to be valid, got %q", message)
+	}
+
+	schedules := Schedules{
+		IncidentsWithoutOwner:        "invalid",
+		TasksWithoutOwner:            "invalid",
+		IncidentsWithClosedTasks:     "invalid",
+		ChangesThatNeedToBeValidated: "invalid",
+		ChangesThatRequireUpdate:     "invalid",
+	}
+	expected := []string{"changesThatNeedToBeValidated", "changesThatRequireUpdate", "incidentsWithClosedTasks", "incidentsWithoutOwner", "tasksWithoutOwner"}
+
+	for i := 0; i < 10; i++ {
+		lines := strings.Split(strings.TrimSpace(schedules.Validate()), "\n")
+		if len(lines) != len(expected) {
+			t.Fatalf("expected a message for each schedule, got %q", lines)
+		}
+		for j, line := range lines {
+			if !strings.HasPrefix(line, "schedules."+expected[j]+" ") {
+				t.Fatalf("expected the messages in the order %v, got %q", expected, lines)
+			}
+		}
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("the time zone database is not available. %v", err)
+	}
+	from := time.Date(2021, 1, 21, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule string
+		expected time.Time
+	}{
+		{"*/15 * * * *", time.Date(2021, 1, 21, 12, 15, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", time.Date(2021, 1, 22, 8, 0, 0, 0, saoPaulo)},
+		{"30 9 * * *", time.Date(2021, 1, 21, 9, 30, 0, 0, saoPaulo)},
+		{"CRON_TZ=UTC 30 9 * * *", time.Date(2021, 1, 22, 9, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 1, 21, 13, 0, 0, 0, time.UTC)},
+		{"@every 10m", from.Add(10 * time.Minute)},
+		{intervalSchedule(5), from.Add(5 * time.Minute)},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.schedule, saoPaulo)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", test.schedule, err)
+			continue
+		}
+		if next := schedule.Next(from); !next.Equal(test.expected) {
+			t.Errorf("expected %q to be next at %v, got %v", test.schedule, test.expected, next)
+		}
+	}
+
+	for _, schedule := range []string{"", "invalid", "* * * *", "61 * * * *", "@every 10"} {
+		if _, err := ParseSchedule(schedule, saoPaulo); err == nil {
+			t.Errorf("expected %q to be rejected", schedule)
+		}
+	}
+}
+
+func TestDatabaseValidateConnection(t *testing.T) {
+	certificate := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(certificate, []byte("certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		database Database
+		message  string
+	}{
+		{"defaults", Database{}, ""},
+		{"port", Database{Port: 1433}, ""},
+		{"negative port", Database{Port: -1}, "database.port is invalid"},
+		{"port too large", Database{Port: 65536}, "database.port is invalid"},
+		{"instance", Database{Server: "cherwell", Instance: "SQLEXPRESS"}, ""},
+		{"instance in the server", Database{Server: `cherwell\SQLEXPRESS`}, ""},
+		{"instance twice", Database{Server: `cherwell\SQLEXPRESS`, Instance: "SQLEXPRESS"}, "database.instance cannot be given"},
+		{"instance with a path", Database{Instance: "SQLEXPRESS/other"}, "database.instance is invalid"},
+		{"instance with a backslash", Database{Instance: `cherwell\SQLEXPRESS`}, "database.instance is invalid"},
+		{"encrypt", Database{Encrypt: "true"}, ""},
+		{"encrypt in uppercase", Database{Encrypt: "Disable"}, ""},
+		{"unknown encrypt", Database{Encrypt: "yes"}, "database.encrypt is invalid"},
+		{"certificate", Database{Encrypt: "true", Certificate: certificate}, ""},
+		{"missing certificate", Database{Certificate: certificate + ".missing"}, "database.certificate is invalid"},
+		{"certificate folder", Database{Certificate: filepath.Dir(certificate)}, "database.certificate is invalid"},
+		{"certificate not verified", Database{Certificate: certificate, TrustServerCertificate: true}, "database.certificate cannot be given"},
+		{"negative connection timeout", Database{ConnectionTimeoutSeconds: -1}, "database.connectionTimeoutSeconds cannot be negative"},
+		{"negative dial timeout", Database{DialTimeoutSeconds: -1}, "database.dialTimeoutSeconds cannot be negative"},
+		{"failover", Database{FailoverPartner: "cherwell-dr", FailoverPort: 1434}, ""},
+		{"failover port without partner", Database{FailoverPort: 1434}, "database.failoverPort requires database.failoverPartner"},
+		{"invalid failover port", Database{FailoverPartner: "cherwell-dr", FailoverPort: 70000}, "database.failoverPort is invalid"},
+		{"params", Database{Params: map[string]string{"packet size": "4096"}}, ""},
+		{"empty param", Database{Params: map[string]string{" ": "1"}}, "database.params cannot have an empty name"},
+		{"param set by a field", Database{Params: map[string]string{"Password": "secret"}}, "database.params cannot have \"Password\""},
+		{"param set by a field with spaces", Database{Params: map[string]string{" encrypt ": "false"}}, "database.params cannot have \" encrypt \""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := test.database.validateConnection()
+			if test.message == "" && message != "" {
+				t.Errorf("expected the connection to be valid, got %q", message)
+			}
+			if !strings.Contains(message, test.message) {
+				t.Errorf("expected the message to contain %q, got %q", test.message, message)
+			}
+		})
+	}
+}
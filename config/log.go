@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Log holds the configuration of the log file
+type Log struct {
+	// Level is the lowest level that is written: "debug", "info", "warn" or "error"
+	Level string
+	// Format is how each entry is written: "text" or "json"
+	Format string
+	// MaxSizeMB is the size at which the log file is rotated, -1 never rotates it
+	MaxSizeMB int `yaml:"maxSizeMB"`
+	// MaxAgeDays is how long the rotated files are kept, -1 keeps them regardless of their age
+	MaxAgeDays int `yaml:"maxAgeDays"`
+	// MaxBackups is how many rotated files are kept, -1 keeps all of them
+	MaxBackups int `yaml:"maxBackups"`
+	// Redact are regular expressions whose matches are masked in the log, besides the passwords and e-mails
+	Redact []string
+}
+
+const (
+	defaultLogLevel      string = "info"
+	defaultLogFormat     string = "text"
+	defaultLogMaxSizeMB  int    = 10
+	defaultLogMaxAgeDays int    = 30
+	defaultLogMaxBackups int    = 5
+
+	// noLogLimit disables the rotation and the removal of the rotated files
+	noLogLimit int = -1
+)
+
+// knownLogLevels and knownLogFormats are the values supported by the logging package
+var (
+	knownLogLevels  = []string{"debug", "info", "warn", "error"}
+	knownLogFormats = []string{"text", "json"}
+)
+
+// GetLevel returns the configured level, falling back to "info" when none is given
+func (l Log) GetLevel() string {
+	if l.Level == "" {
+		return defaultLogLevel
+	}
+	return l.Level
+}
+
+// GetFormat returns the configured format, falling back to "text" when none is given
+func (l Log) GetFormat() string {
+	if l.Format == "" {
+		return defaultLogFormat
+	}
+	return l.Format
+}
+
+// GetMaxSize returns the size in bytes at which the log file is rotated, or 0 when it is never rotated
+func (l Log) GetMaxSize() int64 {
+	if l.MaxSizeMB == 0 {
+		return int64(defaultLogMaxSizeMB) * 1024 * 1024
+	}
+	if l.MaxSizeMB == noLogLimit {
+		return 0
+	}
+	return int64(l.MaxSizeMB) * 1024 * 1024
+}
+
+// GetMaxAge returns how long the rotated files are kept, or 0 when they are kept regardless of their age
+func (l Log) GetMaxAge() time.Duration {
+	if l.MaxAgeDays == 0 {
+		return time.Duration(defaultLogMaxAgeDays) * 24 * time.Hour
+	}
+	if l.MaxAgeDays == noLogLimit {
+		return 0
+	}
+	return time.Duration(l.MaxAgeDays) * 24 * time.Hour
+}
+
+// GetMaxBackups returns how many rotated files are kept, or 0 when all of them are kept
+func (l Log) GetMaxBackups() int {
+	if l.MaxBackups == 0 {
+		return defaultLogMaxBackups
+	}
+	if l.MaxBackups == noLogLimit {
+		return 0
+	}
+	return l.MaxBackups
+}
+
+// Validate validates log values
+func (l Log) Validate() string {
+	validationMessage := ""
+
+	if !contains(knownLogLevels, l.GetLevel()) {
+		validationMessage += fmt.Sprintf("log.level is invalid. Should be one of %v, but got \"%v\"\n", knownLogLevels, l.Level)
+	}
+
+	if !contains(knownLogFormats, l.GetFormat()) {
+		validationMessage += fmt.Sprintf("log.format is invalid. Should be one of %v, but got \"%v\"\n", knownLogFormats, l.Format)
+	}
+
+	if l.MaxSizeMB < noLogLimit {
+		validationMessage += fmt.Sprintln("log.maxSizeMB cannot be negative, except for -1 which disables the rotation")
+	}
+
+	if l.MaxAgeDays < noLogLimit {
+		validationMessage += fmt.Sprintln("log.maxAgeDays cannot be negative, except for -1 which keeps the rotated files regardless of their age")
+	}
+
+	if l.MaxBackups < noLogLimit {
+		validationMessage += fmt.Sprintln("log.maxBackups cannot be negative, except for -1 which keeps all the rotated files")
+	}
+
+	for i, pattern := range l.Redact {
+		if _, err := regexp.Compile(pattern); err != nil {
+			validationMessage += fmt.Sprintf("log.redact[%v] is invalid. %v\n", i, err)
+		}
+	}
+
+	return validationMessage
+}
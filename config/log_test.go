@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        Log
+		maxSize    int64
+		maxAge     time.Duration
+		maxBackups int
+	}{
+		{"defaults", Log{}, 10 * 1024 * 1024, 30 * 24 * time.Hour, 5},
+		{"configured", Log{MaxSizeMB: 1, MaxAgeDays: 2, MaxBackups: 3}, 1024 * 1024, 2 * 24 * time.Hour, 3},
+		{"disabled", Log{MaxSizeMB: -1, MaxAgeDays: -1, MaxBackups: -1}, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		if maxSize := test.log.GetMaxSize(); maxSize != test.maxSize {
+			t.Errorf("%v: expected max size %v, got %v", test.name, test.maxSize, maxSize)
+		}
+		if maxAge := test.log.GetMaxAge(); maxAge != test.maxAge {
+			t.Errorf("%v: expected max age %v, got %v", test.name, test.maxAge, maxAge)
+		}
+		if maxBackups := test.log.GetMaxBackups(); maxBackups != test.maxBackups {
+			t.Errorf("%v: expected max backups %v, got %v", test.name, test.maxBackups, maxBackups)
+		}
+		if message := test.log.Validate(); message != "" {
+			t.Errorf("%v: expected to be valid, got %q", test.name, message)
+		}
+	}
+
+	if message := (Log{MaxSizeMB: -2, MaxAgeDays: -2, MaxBackups: -2}).Validate(); message == "" {
+		t.Error("expected values below -1 to be invalid")
+	}
+}
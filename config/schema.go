@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// identifierRegex matches the names that can be used as tables and columns. A table may be qualified by its schema, such as "dbo.Incidente"
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Schema maps the tables, columns, statuses and priorities used by the built-in notifications onto the customizations
+// of the cherwell database. Every value that is not given falls back to the default cherwell customization.
+type Schema struct {
+	Tables     map[string]string
+	Columns    map[string]map[string]string
+	Statuses   map[string][]string
+	Priorities []int
+}
+
+var defaultSchemaTables = map[string]string{
+	"incident": "Incidente",
+	"task":     "Tarefas",
+	"change":   "Mudanca",
+}
+
+var defaultSchemaColumns = map[string]map[string]string{
+	"incident": {
+		"number":             "NumeroIncidente",
+		"priority":           "Prioridade",
+		"description":        "ShortDescription",
+		"customer":           "CustomerDisplayName",
+		"createdAt":          "CreatedDateTime",
+		"slaRespondDeadline": "SLARespondByDeadline",
+		"slaDeadline":        "SLAResolveByDeadline",
+		"owner":              "OwnedBy",
+		"ownerId":            "OwnerID",
+		"team":               "OwnedByTeam",
+		"status":             "Status",
+		"tasks":              "Tarefas",
+	},
+	"task": {
+		"incidentNumber": "ParentPublicID",
+		"team":           "OwnedByTeam",
+		"status":         "Status",
+		"ownerEmail":     "EmailResponsavel",
+	},
+	"change": {
+		"number":      "NumeroMudanca",
+		"priority":    "Prioridade",
+		"description": "Title",
+		"customer":    "RequestedBy",
+		"createdAt":   "CreatedDateTime",
+		"owner":       "OwnedBy",
+		"team":        "OwnedByTeam",
+		"status":      "Status",
+		"createdBy":   "CreatedBy",
+	},
+}
+
+var defaultSchemaStatuses = map[string][]string{
+	// incidentsWithoutOwner are the statuses of the incidents that are waiting for someone to pick them up
+	"incidentsWithoutOwner": {"Encaminhado", "Novo"},
+	// tasksWithoutOwner are the statuses of the tasks that are waiting for someone to pick them up
+	"tasksWithoutOwner": {"Encaminhada", "Nova"},
+	// incidentsFinished are the statuses of the incidents that no longer need to be closed
+	"incidentsFinished": {"Resolvido", "Fechado"},
+	// tasksClosed are the statuses of the finished tasks
+	"tasksClosed": {"Fechada"},
+	// changesToValidate are the statuses of the changes that were resolved and need to be validated
+	"changesToValidate": {"Resolvida"},
+	// changesRequireUpdate are the statuses of the changes that need to be updated before being approved
+	"changesRequireUpdate": {"Atualização Necessária"},
+}
+
+var defaultSchemaPriorities = []int{1, 2}
+
+// GetTable returns the name of the table of the given entity ("incident", "task" or "change")
+func (s Schema) GetTable(entity string) string {
+	if table, isPresent := s.Tables[entity]; isPresent {
+		return table
+	}
+	return defaultSchemaTables[entity]
+}
+
+// GetColumn returns the name of the column of the given entity and field, such as "incident" and "number"
+func (s Schema) GetColumn(entity string, field string) string {
+	if column, isPresent := s.Columns[entity][field]; isPresent {
+		return column
+	}
+	return defaultSchemaColumns[entity][field]
+}
+
+// GetStatuses returns the statuses of the given set, such as "incidentsWithoutOwner"
+func (s Schema) GetStatuses(set string) []string {
+	if statuses, isPresent := s.Statuses[set]; isPresent {
+		return statuses
+	}
+	return defaultSchemaStatuses[set]
+}
+
+// GetPriorities returns the priorities of the incidents that are notified
+func (s Schema) GetPriorities() []int {
+	if len(s.Priorities) == 0 {
+		return defaultSchemaPriorities
+	}
+	return s.Priorities
+}
+
+// Validate validates schema values. Tables and columns are used in the queries, so only plain identifiers are accepted
+func (s Schema) Validate() string {
+	validationMessage := ""
+
+	for _, entity := range sortedKeys(s.Tables) {
+		if _, isPresent := defaultSchemaTables[entity]; !isPresent {
+			validationMessage += fmt.Sprintf("schema.tables has an unknown table \"%v\". Should be one of %v\n", entity, sortedKeys(defaultSchemaTables))
+		} else if !identifierRegex.MatchString(s.Tables[entity]) {
+			validationMessage += fmt.Sprintf("schema.tables.%v \"%v\" is not a valid table name\n", entity, s.Tables[entity])
+		}
+	}
+
+	for _, entity := range sortedKeys(s.Columns) {
+		defaults, isPresent := defaultSchemaColumns[entity]
+		if !isPresent {
+			validationMessage += fmt.Sprintf("schema.columns has an unknown table \"%v\". Should be one of %v\n", entity, sortedKeys(defaultSchemaTables))
+			continue
+		}
+
+		for _, field := range sortedKeys(s.Columns[entity]) {
+			column := s.Columns[entity][field]
+			if _, isPresent := defaults[field]; !isPresent {
+				validationMessage += fmt.Sprintf("schema.columns.%v has an unknown column \"%v\". Should be one of %v\n", entity, field, sortedKeys(defaults))
+			} else if strings.Contains(column, ".") || !identifierRegex.MatchString(column) {
+				validationMessage += fmt.Sprintf("schema.columns.%v.%v \"%v\" is not a valid column name\n", entity, field, column)
+			}
+		}
+	}
+
+	for _, set := range sortedKeys(s.Statuses) {
+		if _, isPresent := defaultSchemaStatuses[set]; !isPresent {
+			validationMessage += fmt.Sprintf("schema.statuses has an unknown status set \"%v\". Should be one of %v\n", set, sortedKeys(defaultSchemaStatuses))
+		} else if len(s.Statuses[set]) == 0 {
+			validationMessage += fmt.Sprintf("schema.statuses.%v cannot be empty\n", set)
+		}
+	}
+
+	for _, priority := range s.Priorities {
+		if priority < 1 {
+			validationMessage += fmt.Sprintf("schema.priorities has an invalid priority %v. Should be greater than 0\n", priority)
+		}
+	}
+
+	return validationMessage
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch values := m.(type) {
+	case map[string]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]map[string]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string][]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
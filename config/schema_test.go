@@ -0,0 +1,108 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIdentifierRegex(t *testing.T) {
+	tests := []struct {
+		identifier string
+		valid      bool
+	}{
+		{"Incidente", true},
+		{"_Incidente2", true},
+		{"dbo.Incidente", true},
+		{"", false},
+		{"2Incidente", false},
+		{"Incidente i", false},
+		{"Incidente;drop table Incidente", false},
+		{"Incidente; drop table Incidente--", false},
+		{"Incidente--", false},
+		{"Incidente/*", false},
+		{"[Incidente]", false},
+		{"Incidente]; drop table Incidente; --", false},
+		{"Incidente'", false},
+		{"Incidente\"", false},
+		{"Incidente\n", false},
+		{"db.dbo.Incidente", false},
+		{"dbo.", false},
+		{".Incidente", false},
+		{"Incidente(1)", false},
+		{"Incidênte", false},
+	}
+
+	for _, test := range tests {
+		if valid := identifierRegex.MatchString(test.identifier); valid != test.valid {
+			t.Errorf("expected %q to be valid: %v, got %v", test.identifier, test.valid, valid)
+		}
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	valid := Schema{
+		Tables:     map[string]string{"incident": "dbo.Incident"},
+		Columns:    map[string]map[string]string{"incident": {"number": "IncidentID"}},
+		Statuses:   map[string][]string{"tasksClosed": {"Closed", "Cancelled"}},
+		Priorities: []int{1, 2, 3},
+	}
+	if message := valid.Validate(); message != "" {
+		t.Errorf("expected %+v to be valid, got %q", valid, message)
+	}
+
+	tests := []struct {
+		name    string
+		schema  Schema
+		message string
+	}{
+		{"table injection", Schema{Tables: map[string]string{"incident": "Incidente; drop table Incidente"}}, "schema.tables.incident"},
+		{"table bracket", Schema{Tables: map[string]string{"task": "Tarefas]; drop table Tarefas; --"}}, "schema.tables.task"},
+		{"unknown table", Schema{Tables: map[string]string{"problem": "Problema"}}, "unknown table \"problem\""},
+		{"column injection", Schema{Columns: map[string]map[string]string{"incident": {"team": "OwnedByTeam = '' or 1=1 --"}}}, "schema.columns.incident.team"},
+		{"column with table", Schema{Columns: map[string]map[string]string{"change": {"status": "m.Status"}}}, "schema.columns.change.status"},
+		{"unknown column", Schema{Columns: map[string]map[string]string{"incident": {"impact": "Impacto"}}}, "unknown column \"impact\""},
+		{"unknown column table", Schema{Columns: map[string]map[string]string{"problem": {"number": "Numero"}}}, "unknown table \"problem\""},
+		{"unknown status set", Schema{Statuses: map[string][]string{"problemsOpen": {"Aberto"}}}, "unknown status set \"problemsOpen\""},
+		{"empty status set", Schema{Statuses: map[string][]string{"tasksClosed": {}}}, "schema.statuses.tasksClosed cannot be empty"},
+		{"invalid priority", Schema{Priorities: []int{1, 0}}, "invalid priority 0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if message := test.schema.Validate(); !strings.Contains(message, test.message) {
+				t.Errorf("expected the message to contain %q, got %q", test.message, message)
+			}
+		})
+	}
+}
+
+func TestSchemaDefaults(t *testing.T) {
+	schema := Schema{
+		Tables:   map[string]string{"incident": "dbo.Incident"},
+		Columns:  map[string]map[string]string{"incident": {"number": "IncidentID"}},
+		Statuses: map[string][]string{"tasksClosed": {"Closed"}},
+	}
+
+	if table := schema.GetTable("incident"); table != "dbo.Incident" {
+		t.Errorf("expected the customized table, got %v", table)
+	}
+	if table := schema.GetTable("task"); table != "Tarefas" {
+		t.Errorf("expected the default table, got %v", table)
+	}
+	if column := schema.GetColumn("incident", "number"); column != "IncidentID" {
+		t.Errorf("expected the customized column, got %v", column)
+	}
+	if column := schema.GetColumn("incident", "team"); column != "OwnedByTeam" {
+		t.Errorf("expected the default column, got %v", column)
+	}
+	if statuses := schema.GetStatuses("tasksClosed"); !reflect.DeepEqual(statuses, []string{"Closed"}) {
+		t.Errorf("expected the customized statuses, got %v", statuses)
+	}
+	if statuses := schema.GetStatuses("incidentsWithoutOwner"); !reflect.DeepEqual(statuses, []string{"Encaminhado", "Novo"}) {
+		t.Errorf("expected the default statuses, got %v", statuses)
+	}
+	if priorities := schema.GetPriorities(); !reflect.DeepEqual(priorities, []int{1, 2}) {
+		t.Errorf("expected the default priorities, got %v", priorities)
+	}
+}
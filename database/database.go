@@ -1,229 +1,395 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"net"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/logging"
+	"github.com/pedroppinheiro/cwnotifier/secrets"
 )
 
-const (
-	verifyQuerySQL string = "select 1"
-
-	//Chamados prioritários (1,2) que foram encaminhados para a GERIN e que estão sem responsável. Ao se atribuir ao chamado a notificação deve parar
-	getIncidentsWithoutOwnerQuery string = "select NumeroIncidente from Incidente where OwnedByTeam = :team and Prioridade in (1,2) and OwnerID = '' and Status in ('Encaminhado', 'Novo')"
-
-	//Tarefas prioritárias (1 ou 2) para a GERIN que estão sem responsável ou atribuídas para mim. Ao iniciar a tarefa a notificação deve parar
-	getTasksWithoutOwnerQuery string = `select t.ParentPublicID from Tarefas t
-where t.OwnedByTeam = :team
-and t.Status in ('Encaminhada', 'Nova')
-and (t.EmailResponsavel = :email or t.EmailResponsavel = '')
-and (select i.Prioridade from Incidente i where i.NumeroIncidente = t.ParentPublicID) in (1,2)`
-
-	//Chamados prioritários (1 ou 2) para a GERIN que estão atribuídas para mim e que já podem ser concluídas. Ao concluir o chamado ou criar uma nova tarefa a notificação deve parar
-	getIncidentsWithTasksQuery string = `select i.NumeroIncidente, i.Tarefas, count(*) from Incidente i
-join Tarefas t on i.NumeroIncidente = t.ParentPublicID
-where i.OwnedByTeam = :team
-and i.Prioridade in (1,2) 
-and i.Status not in ('Resolvido', 'Fechado')
-and (i.OwnerID = '' or i.OwnedBy = :userName)
-and t.Status = 'Fechada'
-group by i.NumeroIncidente, i.Tarefas, t.Status`
-
-	//Mudanças (RDMs) que precisam ser validadas
-	getChangesThatNeedToBeValidatedQuery string = `select NumeroMudanca from Mudanca where Status = 'Resolvida' and CreatedBy = :userName`
-
-	//Mudanças (RDMs) que estão pendentes de atualização (Atualização Necessária)
-	getChangesThatRequireUpdateQuery string = `select NumeroMudanca from Mudanca where Status = 'Atualização Necessária' and CreatedBy = :userName`
-)
+const verifyQuerySQL string = "select 1"
 
-var connection *sql.DB
+var logger = logging.New("database")
 
-// Connect connects to the database
-func Connect(databaseConfig config.Database) {
-	var errConnect, errVerifyConnection error
+// SQLServer is the data source that reads the items directly from the cherwell SQL Server database
+type SQLServer struct {
+	connection *sql.DB
+	// queries are built from the schema given to Connect
+	queries queries
+	// queryTimeout limits how long each query may run
+	queryTimeout time.Duration
+}
 
-	connection, errConnect = sql.Open(
-		"mssql",
-		fmt.Sprintf("server=%v;port=%v;user id=%v;password=%v;database=%v;", databaseConfig.Server, databaseConfig.Port, databaseConfig.User, databaseConfig.Password, databaseConfig.DatabaseName),
-	)
+// Connect connects to the database, whose tables and columns are described by the schema
+func Connect(ctx context.Context, databaseConfig config.Database, schema config.Schema) (*SQLServer, error) {
+	password, err := secrets.DatabasePassword(databaseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := sql.Open("mssql", connectionString(databaseConfig, password))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error creating connection object. %w", err)
+	}
+
+	s := &SQLServer{
+		connection:   connection,
+		queries:      buildQueries(schema),
+		queryTimeout: databaseConfig.GetQueryTimeout(),
+	}
+
+	err = s.verifyConnection(ctx)
 
-	if errConnect != nil {
-		log.Panic("Error creating connection object. ", errConnect)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("Error connecting to database. %w", err)
+	}
+
+	logger.Infof("Connected successfully to database.")
+	return s, nil
+}
+
+// connectionString builds the connection string of go-mssqldb as an url, so that the values do not need to be escaped.
+// See https://github.com/denisenkom/go-mssqldb#connection-parameters-and-dsn
+func connectionString(databaseConfig config.Database, password string) string {
+	query := url.Values{}
+	for name, value := range databaseConfig.Params {
+		query.Set(name, value)
+	}
+
+	query.Set("database", databaseConfig.DatabaseName)
+	query.Set("app name", databaseConfig.GetAppName())
+	if databaseConfig.Encrypt != "" {
+		query.Set("encrypt", databaseConfig.Encrypt)
+	}
+	if databaseConfig.TrustServerCertificate {
+		query.Set("TrustServerCertificate", "true")
+	}
+	if databaseConfig.Certificate != "" {
+		query.Set("certificate", databaseConfig.Certificate)
+	}
+	if databaseConfig.HostNameInCertificate != "" {
+		query.Set("hostNameInCertificate", databaseConfig.HostNameInCertificate)
+	}
+	if databaseConfig.ConnectionTimeoutSeconds != 0 {
+		query.Set("connection timeout", strconv.Itoa(databaseConfig.ConnectionTimeoutSeconds))
+	}
+	if databaseConfig.DialTimeoutSeconds != 0 {
+		query.Set("dial timeout", strconv.Itoa(databaseConfig.DialTimeoutSeconds))
+	}
+	if databaseConfig.FailoverPartner != "" {
+		query.Set("failoverpartner", databaseConfig.FailoverPartner)
+	}
+	if databaseConfig.FailoverPort != 0 {
+		query.Set("failoverport", strconv.Itoa(databaseConfig.FailoverPort))
 	}
 
-	errVerifyConnection = verifyConnection()
+	// the instance may also be given in the server, as in "server\instance"
+	server, instance := databaseConfig.Server, databaseConfig.Instance
+	if i := strings.Index(server, `\`); i >= 0 {
+		server, instance = server[:i], server[i+1:]
+	}
 
-	if errVerifyConnection != nil {
-		log.Panic("Error connecting to database. ", errVerifyConnection)
+	host := server
+	if databaseConfig.Port != 0 {
+		host = net.JoinHostPort(server, strconv.Itoa(databaseConfig.Port))
 	}
 
-	log.Println("Connected successfully to database.")
+	connectionURL := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(databaseConfig.User, password),
+		Host:     host,
+		RawQuery: query.Encode(),
+	}
+	if instance != "" {
+		connectionURL.Path = "/" + instance
+	}
+	return connectionURL.String()
 }
 
-func verifyConnection() error {
-	_, err := executeQuery(verifyQuerySQL)
-	return err
+func (s *SQLServer) verifyConnection(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.executeQuery(ctx, verifyQuerySQL)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
 }
 
-func executeQuery(query string, args ...interface{}) (*sql.Rows, error) {
-	log.Printf("Executing query \"%v\".", query)
-	return connection.Query(query, args...)
+// executeQuery executes the query, which is cancelled when the context is done.
+// The callers bound the context with queryTimeout and keep it until the rows are read.
+func (s *SQLServer) executeQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if s.connection == nil {
+		return nil, errors.New("There is no connection with the database")
+	}
+
+	if len(args) > 0 {
+		logger.Debugf("Executing query \"%v\" with the arguments %v.", query, logging.Sensitive(fmt.Sprintf("%v", args)))
+	} else {
+		logger.Debugf("Executing query \"%v\".", query)
+	}
+	return s.connection.QueryContext(ctx, query, args...)
 }
 
-// GetIncidentsWithoutOwner returns the incidents without owner
-func GetIncidentsWithoutOwner(teamName string) []string {
+// scanTicket reads the ticket columns of the current row. Extra destinations are scanned after the ticket columns
+func scanTicket(rows *sql.Rows, extra ...interface{}) (datasource.Ticket, error) {
 	var (
-		incidentNumber string
-		results        []string
+		number, priority, description, customer, owner, team sql.NullString
+		createdAt, slaRespondDeadline, slaDeadline           sql.NullTime
 	)
 
-	rows, err := executeQuery(getIncidentsWithoutOwnerQuery, sql.Named("team", teamName))
+	dest := append([]interface{}{&number, &priority, &description, &customer, &createdAt, &slaRespondDeadline, &slaDeadline, &owner, &team}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return datasource.Ticket{}, err
+	}
+
+	return datasource.Ticket{
+		Number:             number.String,
+		Priority:           priority.String,
+		Description:        description.String,
+		Customer:           customer.String,
+		CreatedAt:          createdAt.Time,
+		SLARespondDeadline: slaRespondDeadline.Time,
+		SLADeadline:        slaDeadline.Time,
+		Owner:              owner.String,
+		Team:               team.String,
+	}, nil
+}
+
+// queryTickets executes a query that returns the ticket columns
+func (s *SQLServer) queryTickets(ctx context.Context, errorMessage string, query string, args ...interface{}) ([]datasource.Ticket, error) {
+	var results []datasource.Ticket
+
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.executeQuery(ctx, query, args...)
 
 	if err != nil {
-		log.Panic("Error getting incidents without owner. ", err)
+		return nil, fmt.Errorf("%v %w", errorMessage, err)
 	}
+	defer rows.Close()
+
 	for rows.Next() {
-		err := rows.Scan(&incidentNumber)
+		ticket, err := scanTicket(rows)
 		if err != nil {
-			log.Panic(err)
+			return nil, fmt.Errorf("%v %w", errorMessage, err)
 		}
 
-		results = append(results, incidentNumber)
+		results = append(results, ticket)
+	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%v %w", errorMessage, err)
 	}
 
-	log.Printf("GetIncidentsWithoutOwner: Found %v results: %v", len(results), results)
-	return results
+	return results, nil
 }
 
-// GetTasksWithoutOwner returns the tasks without owner
-func GetTasksWithoutOwner(teamName string, email string) []string {
-	var (
-		taskNumber string
-		results    []string
-	)
-
-	rows, err := executeQuery(getTasksWithoutOwnerQuery, sql.Named("team", teamName), sql.Named("email", email))
+func logResults(functionName string, results []datasource.Ticket) {
+	datasource.LogTickets(logger, functionName, results)
+}
 
+// GetIncidentsWithoutOwner returns the incidents without owner
+func (s *SQLServer) GetIncidentsWithoutOwner(ctx context.Context, teamName string) ([]datasource.Ticket, error) {
+	results, err := s.queryTickets(ctx, "Error getting incidents without owner.", s.queries.incidentsWithoutOwner.text, s.queries.incidentsWithoutOwner.withArgs(sql.Named("team", teamName))...)
 	if err != nil {
-		log.Panic("Error getting tasks without owner. ", err)
+		return nil, err
 	}
-	for rows.Next() {
-		err := rows.Scan(&taskNumber)
-		if err != nil {
-			log.Panic(err)
-		}
 
-		results = append(results, taskNumber)
+	logResults("GetIncidentsWithoutOwner", results)
+	return results, nil
+}
 
+// GetTasksWithoutOwner returns the tasks without owner
+func (s *SQLServer) GetTasksWithoutOwner(ctx context.Context, teamName string, email string) ([]datasource.Ticket, error) {
+	results, err := s.queryTickets(ctx, "Error getting tasks without owner.", s.queries.tasksWithoutOwner.text, s.queries.tasksWithoutOwner.withArgs(sql.Named("team", teamName), sql.Named("email", email))...)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("GetTasksWithoutOwner: Found %v results: %v", len(results), results)
-	return results
+	logResults("GetTasksWithoutOwner", results)
+	return results, nil
 }
 
 // GetIncidentsWithClosedTasks returns incidents with tasks
-func GetIncidentsWithClosedTasks(teamName string, userName string) []string {
+func (s *SQLServer) GetIncidentsWithClosedTasks(ctx context.Context, teamName string, userName string) ([]datasource.Ticket, error) {
 	var (
-		incidentNumber      string
 		taskDescription     string
 		numberOfClosedTasks string
-		results             []string
+		results             []datasource.Ticket
 	)
 
-	rows, err := executeQuery(getIncidentsWithTasksQuery, sql.Named("team", teamName), sql.Named("userName", userName))
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.executeQuery(ctx, s.queries.incidentsWithTasks.text, s.queries.incidentsWithTasks.withArgs(sql.Named("team", teamName), sql.Named("userName", userName))...)
 
 	if err != nil {
-		log.Panic("Error getting incidents with tasks. ", err)
+		return nil, fmt.Errorf("Error getting incidents with tasks. %w", err)
 	}
+	defer rows.Close()
+
 	for rows.Next() {
-		err := rows.Scan(&incidentNumber, &taskDescription, &numberOfClosedTasks)
+		ticket, err := scanTicket(rows, &taskDescription, &numberOfClosedTasks)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting incidents with tasks. %w", err)
+		}
+
+		totalTasks, err := getTotalTasksFromTaskDescription(taskDescription)
 		if err != nil {
-			log.Panic(err)
+			return nil, err
 		}
 
-		if getTotalTasksFromTaskDescription(taskDescription) == numberOfClosedTasks {
-			results = append(results, incidentNumber)
+		if totalTasks == numberOfClosedTasks {
+			results = append(results, ticket)
 		}
 	}
 
-	log.Printf("GetIncidentsWithClosedTasks: Found %v results: %v", len(results), results)
-	return results
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error getting incidents with tasks. %w", err)
+	}
+
+	logResults("GetIncidentsWithClosedTasks", results)
+	return results, nil
 }
 
 var taskDescriptionRegex = regexp.MustCompile(`(?mi)\d+ Fechadas de (?P<totalTasks>\d+) Tarefas`)
 
-func getTotalTasksFromTaskDescription(taskDescription string) string {
+func getTotalTasksFromTaskDescription(taskDescription string) (string, error) {
 	match := taskDescriptionRegex.FindStringSubmatch(taskDescription)
 
 	if match == nil || len(match) == 0 {
-		log.Panicf("invalid task description found, was expecting \"\\d Fechadas de \\d Tarefas\", but found \"%v\"", taskDescription)
+		return "", fmt.Errorf("invalid task description found, was expecting \"\\d Fechadas de \\d Tarefas\", but found \"%v\"", taskDescription)
 	}
 
-	return match[1]
+	return match[1], nil
 }
 
 // GetChangesThatNeedToBeValidated returns changes that need to be validated
-func GetChangesThatNeedToBeValidated(userName string) []string {
-	var (
-		changeNumber string
-		results      []string
-	)
+func (s *SQLServer) GetChangesThatNeedToBeValidated(ctx context.Context, userName string) ([]datasource.Ticket, error) {
+	results, err := s.queryTickets(ctx, "Error getting changes that need to be validated.", s.queries.changesThatNeedToBeValidated.text, s.queries.changesThatNeedToBeValidated.withArgs(sql.Named("userName", userName))...)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := executeQuery(getChangesThatNeedToBeValidatedQuery, sql.Named("userName", userName))
+	logResults("GetChangesThatNeedToBeValidated", results)
+	return results, nil
+}
 
+// GetChangesThatRequireUpdate returns changes that need require update
+func (s *SQLServer) GetChangesThatRequireUpdate(ctx context.Context, userName string) ([]datasource.Ticket, error) {
+	results, err := s.queryTickets(ctx, "Error getting changes that require update.", s.queries.changesThatRequireUpdate.text, s.queries.changesThatRequireUpdate.withArgs(sql.Named("userName", userName))...)
 	if err != nil {
-		log.Panic("Error getting changes that need to be validated. ", err)
+		return nil, err
 	}
-	for rows.Next() {
-		err := rows.Scan(&changeNumber)
-		if err != nil {
-			log.Panic(err)
-		}
 
-		results = append(results, changeNumber)
-	}
+	logResults("GetChangesThatRequireUpdate", results)
+	return results, nil
+}
 
-	log.Printf("GetChangesThatNeedToBeValidated: Found %v results: %v", len(results), results)
-	return results
+// ruleParameterRegex finds the parameters of a rule's query that are bound from the user
+var ruleParameterRegex = regexp.MustCompile(`:(team|email|userName)\b`)
+
+// ruleArgs binds, from the user, only the parameters that appear in the query,
+// since the driver rejects a query that receives more arguments than the parameters it uses
+func ruleArgs(query string, user config.User) []interface{} {
+	values := map[string]string{"team": user.Team, "email": user.Email, "userName": user.Name}
+	bound := make(map[string]bool)
+
+	var args []interface{}
+	for _, match := range ruleParameterRegex.FindAllStringSubmatch(query, -1) {
+		name := match[1]
+		if !bound[name] {
+			bound[name] = true
+			args = append(args, sql.Named(name, values[name]))
+		}
+	}
+	return args
 }
 
-// GetChangesThatRequireUpdate returns changes that need require update
-func GetChangesThatRequireUpdate(userName string) []string {
-	var (
-		changeNumber string
-		results      []string
-	)
+// ExecuteRule executes the query of a user defined rule and returns the values of the rule's column.
+// The parameters :team, :email and :userName used by the query are bound from the given user.
+func (s *SQLServer) ExecuteRule(ctx context.Context, rule config.Rule, user config.User) ([]string, error) {
+	var results []string
+
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
 
-	rows, err := executeQuery(getChangesThatRequireUpdateQuery, sql.Named("userName", userName))
+	rows, err := s.executeQuery(ctx, rule.Query, ruleArgs(rule.Query, user)...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Error executing the query of rule \"%v\". %w", rule.Name, err)
+	}
+	defer rows.Close()
 
+	columns, err := rows.Columns()
 	if err != nil {
-		log.Panic("Error getting changes that require update. ", err)
+		return nil, err
 	}
+
+	columnIndex := -1
+	for i, column := range columns {
+		if strings.EqualFold(column, rule.Column) {
+			columnIndex = i
+		}
+	}
+	if columnIndex == -1 {
+		return nil, fmt.Errorf("The query of rule \"%v\" does not return the column \"%v\". Returned columns: %v", rule.Name, rule.Column, columns)
+	}
+
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
 	for rows.Next() {
-		err := rows.Scan(&changeNumber)
+		err := rows.Scan(scanArgs...)
 		if err != nil {
-			log.Panic(err)
+			return nil, fmt.Errorf("Error executing the query of rule \"%v\". %w", rule.Name, err)
+		}
+
+		if values[columnIndex].Valid {
+			results = append(results, values[columnIndex].String)
 		}
+	}
 
-		results = append(results, changeNumber)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error executing the query of rule \"%v\". %w", rule.Name, err)
 	}
 
-	log.Printf("GetChangesThatRequireUpdate: Found %v results: %v", len(results), results)
-	return results
+	logger.Infof("Rule %v: Found %v results: %v", rule.Name, len(results), results)
+	return results, nil
 }
 
-// CloseConnection closes the connection
-func CloseConnection() {
-	log.Println("Closing the connection with database.")
-	err := connection.Close()
+// Close closes the connection
+func (s *SQLServer) Close() {
+	if s.connection == nil {
+		return
+	}
+
+	logger.Infof("Closing the connection with database.")
+	err := s.connection.Close()
+	s.connection = nil
 
 	if err != nil {
-		log.Println("Error during closing connection with db. ", err)
+		logger.Errorf("Error during closing connection with db. %v", err)
 	} else {
-		log.Println("Connection with database was closed.")
+		logger.Infof("Connection with database was closed.")
 	}
 }
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// query is a query built from the schema. The values of its status and priority sets are bound as parameters
+type query struct {
+	text string
+	args []interface{}
+}
+
+// queries holds the queries of the built-in notifications
+type queries struct {
+	incidentsWithoutOwner        query
+	tasksWithoutOwner            query
+	incidentsWithTasks           query
+	changesThatNeedToBeValidated query
+	changesThatRequireUpdate     query
+}
+
+// queryBuilder collects the parameters of a query as it is built
+type queryBuilder struct {
+	args []interface{}
+}
+
+// in binds the values as parameters and returns the list of the parameters, such as "(:p0, :p1)"
+func (b *queryBuilder) in(values ...interface{}) string {
+	names := make([]string, len(values))
+	for i, value := range values {
+		name := fmt.Sprintf("p%v", len(b.args))
+		b.args = append(b.args, sql.Named(name, value))
+		names[i] = ":" + name
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func (b *queryBuilder) query(text string) query {
+	return query{text: text, args: b.args}
+}
+
+// quote quotes an identifier of the schema, which is validated by config.Schema to be a plain name, such as "dbo.Incidente"
+func quote(identifier string) string {
+	return "[" + strings.Replace(identifier, ".", "].[", -1) + "]"
+}
+
+func stringValues(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+func intValues(values []int) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// buildQueries builds the queries of the built-in notifications from the tables, columns, statuses and priorities of the schema
+func buildQueries(schema config.Schema) queries {
+	incident := func(field string) string { return "i." + quote(schema.GetColumn("incident", field)) }
+	task := func(field string) string { return "t." + quote(schema.GetColumn("task", field)) }
+	change := func(field string) string { return "m." + quote(schema.GetColumn("change", field)) }
+	statuses := func(set string) []interface{} { return stringValues(schema.GetStatuses(set)) }
+	priorities := intValues(schema.GetPriorities())
+
+	incidentTable := quote(schema.GetTable("incident")) + " i"
+	taskTable := quote(schema.GetTable("task")) + " t"
+	changeTable := quote(schema.GetTable("change")) + " m"
+
+	// incidentColumns are the columns of the incident table that are read into a datasource.Ticket
+	incidentColumns := strings.Join([]string{
+		incident("number"), incident("priority"), incident("description"), incident("customer"), incident("createdAt"),
+		incident("slaRespondDeadline"), incident("slaDeadline"), incident("owner"), incident("team"),
+	}, ", ")
+
+	// changeColumns are the columns of the change table that are read into a datasource.Ticket
+	changeColumns := strings.Join([]string{
+		change("number"), change("priority"), change("description"), change("customer"), change("createdAt"),
+		"null", "null", change("owner"), change("team"),
+	}, ", ")
+
+	var q queries
+
+	//Chamados prioritários que foram encaminhados para a equipe e que estão sem responsável. Ao se atribuir ao chamado a notificação deve parar
+	b := queryBuilder{}
+	q.incidentsWithoutOwner = b.query("select " + incidentColumns + " from " + incidentTable +
+		" where " + incident("team") + " = :team and " + incident("priority") + " in " + b.in(priorities...) +
+		" and " + incident("ownerId") + " = '' and " + incident("status") + " in " + b.in(statuses("incidentsWithoutOwner")...))
+
+	//Tarefas prioritárias para a equipe que estão sem responsável ou atribuídas para mim. Ao iniciar a tarefa a notificação deve parar
+	b = queryBuilder{}
+	q.tasksWithoutOwner = b.query("select " + incidentColumns + " from " + taskTable + `
+join ` + incidentTable + " on " + incident("number") + " = " + task("incidentNumber") + `
+where ` + task("team") + ` = :team
+and ` + task("status") + " in " + b.in(statuses("tasksWithoutOwner")...) + `
+and (` + task("ownerEmail") + " = :email or " + task("ownerEmail") + ` = '')
+and ` + incident("priority") + " in " + b.in(priorities...))
+
+	//Chamados prioritários para a equipe que estão atribuídos para mim e que já podem ser concluídos. Ao concluir o chamado ou criar uma nova tarefa a notificação deve parar
+	b = queryBuilder{}
+	q.incidentsWithTasks = b.query("select " + incidentColumns + ", " + incident("tasks") + ", count(*) from " + incidentTable + `
+join ` + taskTable + " on " + incident("number") + " = " + task("incidentNumber") + `
+where ` + incident("team") + ` = :team
+and ` + incident("priority") + " in " + b.in(priorities...) + `
+and ` + incident("status") + " not in " + b.in(statuses("incidentsFinished")...) + `
+and (` + incident("ownerId") + " = '' or " + incident("owner") + ` = :userName)
+and ` + task("status") + " in " + b.in(statuses("tasksClosed")...) + `
+group by ` + incidentColumns + ", " + incident("tasks"))
+
+	//Mudanças (RDMs) que precisam ser validadas
+	b = queryBuilder{}
+	q.changesThatNeedToBeValidated = b.query("select " + changeColumns + " from " + changeTable +
+		" where " + change("status") + " in " + b.in(statuses("changesToValidate")...) + " and " + change("createdBy") + " = :userName")
+
+	//Mudanças (RDMs) que estão pendentes de atualização (Atualização Necessária)
+	b = queryBuilder{}
+	q.changesThatRequireUpdate = b.query("select " + changeColumns + " from " + changeTable +
+		" where " + change("status") + " in " + b.in(statuses("changesRequireUpdate")...) + " and " + change("createdBy") + " = :userName")
+
+	return q
+}
+
+// withArgs returns the parameters of the query followed by the given ones
+func (q query) withArgs(args ...interface{}) []interface{} {
+	return append(append([]interface{}{}, q.args...), args...)
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// placeholderRegex finds the parameters bound by queryBuilder.in
+var placeholderRegex = regexp.MustCompile(`:p[0-9]+`)
+
+// inline replaces the :p0..:pN parameters of the query with the values bound to them, failing the test when
+// the parameters are not named in the order they appear in the query or when a parameter has no value
+func inline(t *testing.T, q query) string {
+	t.Helper()
+	values := make(map[string]string)
+	for i, arg := range q.args {
+		named, isNamed := arg.(sql.NamedArg)
+		if !isNamed || named.Name != fmt.Sprintf("p%v", i) {
+			t.Fatalf("expected the argument %v to be named p%v, got %#v", i, i, arg)
+		}
+		switch value := named.Value.(type) {
+		case string:
+			values[":"+named.Name] = "'" + value + "'"
+		default:
+			values[":"+named.Name] = fmt.Sprint(value)
+		}
+	}
+
+	placeholders := placeholderRegex.FindAllString(q.text, -1)
+	if len(placeholders) != len(q.args) {
+		t.Fatalf("expected a placeholder for each of the %v arguments, got %v", len(q.args), placeholders)
+	}
+	for i, placeholder := range placeholders {
+		if placeholder != fmt.Sprintf(":p%v", i) {
+			t.Fatalf("expected the placeholder %v to be :p%v, got %v", i, i, placeholder)
+		}
+	}
+	return placeholderRegex.ReplaceAllStringFunc(q.text, func(placeholder string) string { return values[placeholder] })
+}
+
+// normalize removes the quotes of the identifiers and the differences of spacing, so that queries can be compared with the former ones
+func normalize(text string) string {
+	text = strings.NewReplacer("[", "", "]", "", ", ", ",").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func namedQueries(q queries) map[string]query {
+	return map[string]query{
+		"incidentsWithoutOwner":        q.incidentsWithoutOwner,
+		"tasksWithoutOwner":            q.tasksWithoutOwner,
+		"incidentsWithTasks":           q.incidentsWithTasks,
+		"changesThatNeedToBeValidated": q.changesThatNeedToBeValidated,
+		"changesThatRequireUpdate":     q.changesThatRequireUpdate,
+	}
+}
+
+func TestBuildQueriesDefaultSchema(t *testing.T) {
+	incidentColumns := "i.[NumeroIncidente], i.[Prioridade], i.[ShortDescription], i.[CustomerDisplayName], i.[CreatedDateTime], i.[SLARespondByDeadline], i.[SLAResolveByDeadline], i.[OwnedBy], i.[OwnedByTeam]"
+	changeColumns := "m.[NumeroMudanca], m.[Prioridade], m.[Title], m.[RequestedBy], m.[CreatedDateTime], null, null, m.[OwnedBy], m.[OwnedByTeam]"
+
+	expected := map[string]query{
+		"incidentsWithoutOwner": {
+			text: "select " + incidentColumns + " from [Incidente] i where i.[OwnedByTeam] = :team and i.[Prioridade] in (:p0, :p1) and i.[OwnerID] = '' and i.[Status] in (:p2, :p3)",
+			args: []interface{}{sql.Named("p0", 1), sql.Named("p1", 2), sql.Named("p2", "Encaminhado"), sql.Named("p3", "Novo")},
+		},
+		"tasksWithoutOwner": {
+			text: "select " + incidentColumns + " from [Tarefas] t\n" +
+				"join [Incidente] i on i.[NumeroIncidente] = t.[ParentPublicID]\n" +
+				"where t.[OwnedByTeam] = :team\n" +
+				"and t.[Status] in (:p0, :p1)\n" +
+				"and (t.[EmailResponsavel] = :email or t.[EmailResponsavel] = '')\n" +
+				"and i.[Prioridade] in (:p2, :p3)",
+			args: []interface{}{sql.Named("p0", "Encaminhada"), sql.Named("p1", "Nova"), sql.Named("p2", 1), sql.Named("p3", 2)},
+		},
+		"incidentsWithTasks": {
+			text: "select " + incidentColumns + ", i.[Tarefas], count(*) from [Incidente] i\n" +
+				"join [Tarefas] t on i.[NumeroIncidente] = t.[ParentPublicID]\n" +
+				"where i.[OwnedByTeam] = :team\n" +
+				"and i.[Prioridade] in (:p0, :p1)\n" +
+				"and i.[Status] not in (:p2, :p3)\n" +
+				"and (i.[OwnerID] = '' or i.[OwnedBy] = :userName)\n" +
+				"and t.[Status] in (:p4)\n" +
+				"group by " + incidentColumns + ", i.[Tarefas]",
+			args: []interface{}{sql.Named("p0", 1), sql.Named("p1", 2), sql.Named("p2", "Resolvido"), sql.Named("p3", "Fechado"), sql.Named("p4", "Fechada")},
+		},
+		"changesThatNeedToBeValidated": {
+			text: "select " + changeColumns + " from [Mudanca] m where m.[Status] in (:p0) and m.[CreatedBy] = :userName",
+			args: []interface{}{sql.Named("p0", "Resolvida")},
+		},
+		"changesThatRequireUpdate": {
+			text: "select " + changeColumns + " from [Mudanca] m where m.[Status] in (:p0) and m.[CreatedBy] = :userName",
+			args: []interface{}{sql.Named("p0", "Atualização Necessária")},
+		},
+	}
+
+	for name, q := range namedQueries(buildQueries(config.Schema{})) {
+		if q.text != expected[name].text {
+			t.Errorf("%v: expected the query\n%v\ngot\n%v", name, expected[name].text, q.text)
+		}
+		if !reflect.DeepEqual(q.args, expected[name].args) {
+			t.Errorf("%v: expected the arguments %v, got %v", name, expected[name].args, q.args)
+		}
+	}
+}
+
+func TestBuildQueriesMatchTheFormerQueries(t *testing.T) {
+	// the queries that were written by hand before the schema could be customized
+	incidentColumns := "i.NumeroIncidente, i.Prioridade, i.ShortDescription, i.CustomerDisplayName, i.CreatedDateTime, i.SLARespondByDeadline, i.SLAResolveByDeadline, i.OwnedBy, i.OwnedByTeam"
+	changeColumns := "m.NumeroMudanca, m.Prioridade, m.Title, m.RequestedBy, m.CreatedDateTime, null, null, m.OwnedBy, m.OwnedByTeam"
+	former := map[string]string{
+		"incidentsWithoutOwner": "select " + incidentColumns + " from Incidente i where i.OwnedByTeam = :team and i.Prioridade in (1,2) and i.OwnerID = '' and i.Status in ('Encaminhado', 'Novo')",
+		"tasksWithoutOwner": `select ` + incidentColumns + ` from Tarefas t
+join Incidente i on i.NumeroIncidente = t.ParentPublicID
+where t.OwnedByTeam = :team
+and t.Status in ('Encaminhada', 'Nova')
+and (t.EmailResponsavel = :email or t.EmailResponsavel = '')
+and i.Prioridade in (1,2)`,
+		// the closed tasks are now a set of statuses, so they are no longer compared with "=" nor grouped by,
+		// which would count each status apart. With the single default status both return the same rows.
+		"incidentsWithTasks": `select ` + incidentColumns + `, i.Tarefas, count(*) from Incidente i
+join Tarefas t on i.NumeroIncidente = t.ParentPublicID
+where i.OwnedByTeam = :team
+and i.Prioridade in (1,2)
+and i.Status not in ('Resolvido', 'Fechado')
+and (i.OwnerID = '' or i.OwnedBy = :userName)
+and t.Status in ('Fechada')
+group by ` + incidentColumns + `, i.Tarefas`,
+		"changesThatNeedToBeValidated": "select " + changeColumns + " from Mudanca m where m.Status in ('Resolvida') and m.CreatedBy = :userName",
+		"changesThatRequireUpdate":     "select " + changeColumns + " from Mudanca m where m.Status in ('Atualização Necessária') and m.CreatedBy = :userName",
+	}
+
+	for name, q := range namedQueries(buildQueries(config.Schema{})) {
+		if text := normalize(inline(t, q)); text != normalize(former[name]) {
+			t.Errorf("%v: expected the query\n%v\ngot\n%v", name, normalize(former[name]), text)
+		}
+	}
+}
+
+func TestBuildQueriesCustomSchema(t *testing.T) {
+	schema := config.Schema{
+		Tables: map[string]string{"incident": "dbo.Incident", "task": "dbo.Task"},
+		Columns: map[string]map[string]string{
+			"incident": {"number": "IncidentID", "team": "Team"},
+			"task":     {"incidentNumber": "IncidentID"},
+			"change":   {"createdBy": "Requester"},
+		},
+		Statuses: map[string][]string{
+			"incidentsWithoutOwner": {"Assigned", "New", "Reopened"},
+			"tasksClosed":           {"Closed", "Cancelled"},
+			"changesToValidate":     {"Done"},
+		},
+		Priorities: []int{1, 2, 3},
+	}
+	incidentColumns := "i.[IncidentID], i.[Prioridade], i.[ShortDescription], i.[CustomerDisplayName], i.[CreatedDateTime], i.[SLARespondByDeadline], i.[SLAResolveByDeadline], i.[OwnedBy], i.[Team]"
+	changeColumns := "m.[NumeroMudanca], m.[Prioridade], m.[Title], m.[RequestedBy], m.[CreatedDateTime], null, null, m.[OwnedBy], m.[OwnedByTeam]"
+
+	expected := map[string]string{
+		"incidentsWithoutOwner": "select " + incidentColumns + " from [dbo].[Incident] i where i.[Team] = :team and i.[Prioridade] in (1, 2, 3) and i.[OwnerID] = '' and i.[Status] in ('Assigned', 'New', 'Reopened')",
+		"tasksWithoutOwner": "select " + incidentColumns + " from [dbo].[Task] t\n" +
+			"join [dbo].[Incident] i on i.[IncidentID] = t.[IncidentID]\n" +
+			"where t.[OwnedByTeam] = :team\n" +
+			"and t.[Status] in ('Encaminhada', 'Nova')\n" +
+			"and (t.[EmailResponsavel] = :email or t.[EmailResponsavel] = '')\n" +
+			"and i.[Prioridade] in (1, 2, 3)",
+		"incidentsWithTasks": "select " + incidentColumns + ", i.[Tarefas], count(*) from [dbo].[Incident] i\n" +
+			"join [dbo].[Task] t on i.[IncidentID] = t.[IncidentID]\n" +
+			"where i.[Team] = :team\n" +
+			"and i.[Prioridade] in (1, 2, 3)\n" +
+			"and i.[Status] not in ('Resolvido', 'Fechado')\n" +
+			"and (i.[OwnerID] = '' or i.[OwnedBy] = :userName)\n" +
+			"and t.[Status] in ('Closed', 'Cancelled')\n" +
+			"group by " + incidentColumns + ", i.[Tarefas]",
+		"changesThatNeedToBeValidated": "select " + changeColumns + " from [Mudanca] m where m.[Status] in ('Done') and m.[Requester] = :userName",
+		"changesThatRequireUpdate":     "select " + changeColumns + " from [Mudanca] m where m.[Status] in ('Atualização Necessária') and m.[Requester] = :userName",
+	}
+
+	for name, q := range namedQueries(buildQueries(schema)) {
+		if text := inline(t, q); text != expected[name] {
+			t.Errorf("%v: expected the query\n%v\ngot\n%v", name, expected[name], text)
+		}
+	}
+}
+
+func TestQueryBuilderIn(t *testing.T) {
+	b := queryBuilder{}
+
+	if list := b.in("a", "b"); list != "(:p0, :p1)" {
+		t.Errorf("expected the first values to be bound as (:p0, :p1), got %v", list)
+	}
+	if list := b.in(3); list != "(:p2)" {
+		t.Errorf("expected the numbering to continue at :p2, got %v", list)
+	}
+
+	expected := []interface{}{sql.Named("p0", "a"), sql.Named("p1", "b"), sql.Named("p2", 3)}
+	if !reflect.DeepEqual(b.args, expected) {
+		t.Errorf("expected the arguments %v, got %v", expected, b.args)
+	}
+}
+
+func TestWithArgs(t *testing.T) {
+	b := queryBuilder{}
+	q := b.query("select 1 where a in " + b.in("x", "y") + " and team = :team")
+
+	args := q.withArgs(sql.Named("team", "Support"))
+	expected := []interface{}{sql.Named("p0", "x"), sql.Named("p1", "y"), sql.Named("team", "Support")}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected the arguments %v, got %v", expected, args)
+	}
+
+	// the arguments of the query are not shared between calls
+	q.withArgs(sql.Named("team", "Network"))
+	if again := q.withArgs(sql.Named("team", "Support")); !reflect.DeepEqual(again, expected) {
+		t.Errorf("expected the arguments of the query to be kept, got %v", again)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"Incidente":     "[Incidente]",
+		"dbo.Incidente": "[dbo].[Incidente]",
+	}
+
+	for identifier, expected := range tests {
+		if quoted := quote(identifier); quoted != expected {
+			t.Errorf("expected %v to be quoted as %v, got %v", identifier, expected, quoted)
+		}
+	}
+}
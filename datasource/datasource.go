@@ -0,0 +1,32 @@
+package datasource
+
+import (
+	"context"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// DataSource provides the cherwell items that are checked by the notifications.
+// The methods return an error when the items could not be read, such as when the connection was lost,
+// or when the context is done before the items are read.
+// It is implemented by the SQL Server database, by the cherwell REST API and by Memory, which holds fixed items.
+type DataSource interface {
+	// GetIncidentsWithoutOwner returns the priority incidents of the team that have no owner
+	GetIncidentsWithoutOwner(ctx context.Context, teamName string) ([]Ticket, error)
+	// GetTasksWithoutOwner returns the incidents of the priority tasks of the team that have no owner or are owned by the given email
+	GetTasksWithoutOwner(ctx context.Context, teamName string, email string) ([]Ticket, error)
+	// GetIncidentsWithClosedTasks returns the priority incidents of the team whose tasks are all closed
+	GetIncidentsWithClosedTasks(ctx context.Context, teamName string, userName string) ([]Ticket, error)
+	// GetChangesThatNeedToBeValidated returns the changes created by the user that were resolved
+	GetChangesThatNeedToBeValidated(ctx context.Context, userName string) ([]Ticket, error)
+	// GetChangesThatRequireUpdate returns the changes created by the user that require update
+	GetChangesThatRequireUpdate(ctx context.Context, userName string) ([]Ticket, error)
+	// Close releases the resources held by the data source
+	Close()
+}
+
+// RuleExecutor is implemented by the data sources that support the user defined rules, whose queries are written for the SQL Server database
+type RuleExecutor interface {
+	// ExecuteRule returns the values of the rule's column in the rows returned by the rule's query for the user
+	ExecuteRule(ctx context.Context, rule config.Rule, user config.User) ([]string, error)
+}
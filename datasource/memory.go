@@ -0,0 +1,148 @@
+package datasource
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Memory is the data source that returns the items it holds, such as the ones of a fixture file read by ReadFixture.
+// It allows running the notifications without cherwell, such as to try the configuration or to test the notification logic.
+// The tickets are filtered like the queries of the database do, except that an empty team or owner matches any user:
+// the tickets that have a team are only returned for that team and the owner of a ticket is compared with the user's
+// e-mail for the tasks, with the user's name for the incidents with closed tasks and, since the changes are the ones
+// created by the user, it stands for the creator of the changes.
+type Memory struct {
+	IncidentsWithoutOwner        []Ticket
+	TasksWithoutOwner            []Ticket
+	IncidentsWithClosedTasks     []Ticket
+	ChangesThatNeedToBeValidated []Ticket
+	ChangesThatRequireUpdate     []Ticket
+	// Rules holds the values returned by each rule, by the name of the rule
+	Rules map[string][]string
+	// Err is returned by every method when it is set, such as to simulate a lost connection
+	Err error
+}
+
+// GetIncidentsWithoutOwner returns the incidents without owner of the team
+func (m *Memory) GetIncidentsWithoutOwner(ctx context.Context, teamName string) ([]Ticket, error) {
+	return m.tickets(ctx, m.IncidentsWithoutOwner, teamName, "")
+}
+
+// GetTasksWithoutOwner returns the incidents of the tasks of the team without owner or owned by the e-mail
+func (m *Memory) GetTasksWithoutOwner(ctx context.Context, teamName string, email string) ([]Ticket, error) {
+	return m.tickets(ctx, m.TasksWithoutOwner, teamName, email)
+}
+
+// GetIncidentsWithClosedTasks returns the incidents of the team, without owner or owned by the user, whose tasks are all closed
+func (m *Memory) GetIncidentsWithClosedTasks(ctx context.Context, teamName string, userName string) ([]Ticket, error) {
+	return m.tickets(ctx, m.IncidentsWithClosedTasks, teamName, userName)
+}
+
+// GetChangesThatNeedToBeValidated returns the changes created by the user that need to be validated
+func (m *Memory) GetChangesThatNeedToBeValidated(ctx context.Context, userName string) ([]Ticket, error) {
+	return m.tickets(ctx, m.ChangesThatNeedToBeValidated, "", userName)
+}
+
+// GetChangesThatRequireUpdate returns the changes created by the user that require update
+func (m *Memory) GetChangesThatRequireUpdate(ctx context.Context, userName string) ([]Ticket, error) {
+	return m.tickets(ctx, m.ChangesThatRequireUpdate, "", userName)
+}
+
+// ExecuteRule returns the values held for the rule
+func (m *Memory) ExecuteRule(ctx context.Context, rule config.Rule, user config.User) ([]string, error) {
+	if err := m.err(ctx); err != nil {
+		return nil, err
+	}
+	return m.Rules[rule.Name], nil
+}
+
+// Close does nothing, since there is nothing to release
+func (m *Memory) Close() {}
+
+func (m *Memory) err(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Err
+}
+
+// tickets returns the tickets of the team and the owner. An empty team or owner, either in the ticket or given, matches any one
+func (m *Memory) tickets(ctx context.Context, tickets []Ticket, teamName string, owner string) ([]Ticket, error) {
+	if err := m.err(ctx); err != nil {
+		return nil, err
+	}
+
+	var results []Ticket
+	for _, ticket := range tickets {
+		if matches(ticket.Team, teamName) && matches(ticket.Owner, owner) {
+			results = append(results, ticket)
+		}
+	}
+
+	RegisterPersonalData(results)
+	return results, nil
+}
+
+// matches compares the value of a ticket with the one given, ignoring the case as SQL Server does
+func matches(value string, given string) bool {
+	return value == "" || given == "" || strings.EqualFold(value, given)
+}
+
+// fixture is the content of a fixture file
+type fixture struct {
+	IncidentsWithoutOwner        []fixtureTicket     `yaml:"incidentsWithoutOwner"`
+	TasksWithoutOwner            []fixtureTicket     `yaml:"tasksWithoutOwner"`
+	IncidentsWithClosedTasks     []fixtureTicket     `yaml:"incidentsWithClosedTasks"`
+	ChangesThatNeedToBeValidated []fixtureTicket     `yaml:"changesThatNeedToBeValidated"`
+	ChangesThatRequireUpdate     []fixtureTicket     `yaml:"changesThatRequireUpdate"`
+	Rules                        map[string][]string `yaml:"rules"`
+}
+
+// fixtureTicket is a ticket of a fixture file. The dates are in RFC 3339 format, such as "2021-01-21T14:00:00-03:00"
+type fixtureTicket struct {
+	Number             string
+	Priority           string
+	Description        string
+	Customer           string
+	CreatedAt          time.Time `yaml:"createdAt"`
+	SLARespondDeadline time.Time `yaml:"slaRespondDeadline"`
+	SLADeadline        time.Time `yaml:"slaDeadline"`
+	Owner              string
+	Team               string
+}
+
+// ReadFixture reads the items of a YAML fixture file, which has a list of tickets for each notification
+// and the values of each rule, by the name of the rule
+func ReadFixture(fixtureLocation string) (*Memory, error) {
+	content, err := ioutil.ReadFile(fixtureLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var f fixture
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, err
+	}
+
+	return &Memory{
+		IncidentsWithoutOwner:        fixtureTickets(f.IncidentsWithoutOwner),
+		TasksWithoutOwner:            fixtureTickets(f.TasksWithoutOwner),
+		IncidentsWithClosedTasks:     fixtureTickets(f.IncidentsWithClosedTasks),
+		ChangesThatNeedToBeValidated: fixtureTickets(f.ChangesThatNeedToBeValidated),
+		ChangesThatRequireUpdate:     fixtureTickets(f.ChangesThatRequireUpdate),
+		Rules:                        f.Rules,
+	}, nil
+}
+
+func fixtureTickets(fixtureTickets []fixtureTicket) []Ticket {
+	tickets := make([]Ticket, len(fixtureTickets))
+	for i, t := range fixtureTickets {
+		tickets[i] = Ticket(t)
+	}
+	return tickets
+}
@@ -0,0 +1,88 @@
+package datasource
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+// maxDescriptionLength limits the description shown in the summary, so that the notification remains readable
+const maxDescriptionLength int = 60
+
+// Ticket holds the details of a cherwell incident or change
+type Ticket struct {
+	Number      string
+	Priority    string
+	Description string
+	Customer    string
+	CreatedAt   time.Time
+	// SLARespondDeadline is when the ticket must be responded (picked up by someone), zero if unknown
+	SLARespondDeadline time.Time
+	// SLADeadline is when the ticket must be resolved, zero if unknown
+	SLADeadline time.Time
+	Owner       string
+	Team        string
+}
+
+// Summary returns a single line description of the ticket, such as "12345 [P1] Sistema fora do ar - Fulano | Suporte: Beltrano (SLA 21/01 14:00)",
+// where the team and the owner are the ones the ticket is assigned to, when they are known
+func (t Ticket) Summary() string {
+	var summary strings.Builder
+	summary.WriteString(t.Number)
+
+	if t.Priority != "" {
+		fmt.Fprintf(&summary, " [P%v]", t.Priority)
+	}
+
+	if t.Description != "" {
+		summary.WriteString(" " + truncate(t.Description, maxDescriptionLength))
+	}
+
+	if t.Customer != "" {
+		summary.WriteString(" - " + t.Customer)
+	}
+
+	switch {
+	case t.Team != "" && t.Owner != "":
+		summary.WriteString(" | " + t.Team + ": " + t.Owner)
+	case t.Team != "":
+		summary.WriteString(" | " + t.Team)
+	case t.Owner != "":
+		summary.WriteString(" | " + t.Owner)
+	}
+
+	if !t.SLADeadline.IsZero() {
+		summary.WriteString(" (SLA " + t.SLADeadline.Format("02/01 15:04") + ")")
+	}
+
+	return summary.String()
+}
+
+// RegisterPersonalData masks the names of the customers and owners of the tickets in the log, keeping the rest of the tickets readable.
+// The data sources call it for the tickets they read, before the tickets are logged or notified.
+func RegisterPersonalData(tickets []Ticket) {
+	for _, t := range tickets {
+		logging.AddRecentPersonalData(t.Customer, t.Owner)
+	}
+}
+
+// LogTickets writes the summaries of the tickets found to the log, masking their personal data
+func LogTickets(logger *logging.Logger, name string, tickets []Ticket) {
+	RegisterPersonalData(tickets)
+
+	logger.Infof("%v: Found %v results:", name, len(tickets))
+	for _, t := range tickets {
+		logger.Infof("    %v", t.Summary())
+	}
+}
+
+func truncate(s string, length int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-3]) + "..."
+}
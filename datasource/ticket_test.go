@@ -0,0 +1,32 @@
+package datasource
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTicketSummary(t *testing.T) {
+	deadline := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		ticket   Ticket
+		expected string
+	}{
+		{"number", Ticket{Number: "12345"}, "12345"},
+		{"all", Ticket{Number: "12345", Priority: "1", Description: "Sistema fora do ar", Customer: "Fulano", Team: "Suporte", Owner: "Beltrano", SLADeadline: deadline}, "12345 [P1] Sistema fora do ar - Fulano | Suporte: Beltrano (SLA 21/01 14:00)"},
+		{"without owner", Ticket{Number: "12345", Priority: "2", Description: "Lentidão", Customer: "Fulano", Team: "Suporte"}, "12345 [P2] Lentidão - Fulano | Suporte"},
+		{"without team", Ticket{Number: "12345", Customer: "Fulano", Owner: "Beltrano"}, "12345 - Fulano | Beltrano"},
+		{"spaces", Ticket{Number: "12345", Description: "  Sistema\n fora   do ar "}, "12345 Sistema fora do ar"},
+		{"long description", Ticket{Number: "12345", Description: strings.Repeat("a", 70)}, "12345 " + strings.Repeat("a", 57) + "..."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if summary := test.ticket.Summary(); summary != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, summary)
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 module github.com/pedroppinheiro/cwnotifier
 
-go 1.15
+go 1.16
 
 require (
 	github.com/denisenkom/go-mssqldb v0.9.0
 	github.com/getlantern/systray v1.1.0
+	github.com/godbus/dbus/v5 v5.0.6
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
+	github.com/robfig/cron/v3 v3.0.1
 	gopkg.in/toast.v1 v1.0.0-20180812000517-0a84660828b2
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/systemd"
+)
+
+// runHeadless runs the program without the system tray until it is interrupted or terminated.
+// When it is supervised by systemd, the service manager is told when the program is ready and is stopping,
+// and the watchdog is notified while no check is stuck.
+func runHeadless() int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopOnSignal(cancel)
+
+	ready := func() {
+		logger.Infof("CWNotifier is running without the system tray.")
+		notifySystemd(systemd.Ready)
+		if interval := systemd.WatchdogInterval(); interval > 0 {
+			go notifyWatchdog(ctx, interval, runningChecks)
+		}
+	}
+
+	err := run(ctx, ready)
+	notifySystemd(systemd.Stopping)
+
+	if err != nil {
+		logger.Errorf("%v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// notifyWatchdog tells systemd that the program is alive on every interval, until the context is done.
+// The watchdog is not notified while an attempt of a check is running for longer than the watchdog timeout, which is twice the interval,
+// so that systemd restarts the program when a query hangs or the checks are deadlocked.
+func notifyWatchdog(ctx context.Context, interval time.Duration, checks *checkActivity) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timeout := 2 * interval
+	for {
+		select {
+		case now := <-ticker.C:
+			if since := checks.oldest(); !since.IsZero() && now.Sub(since) >= timeout {
+				logger.Errorf("A check is running since %v, longer than the watchdog timeout of %v. The watchdog is no longer notified.", since.Format("15:04:05"), timeout)
+				continue
+			}
+			notifySystemd(systemd.Watchdog)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkActivity keeps the start time of the attempts of the checks in progress. An attempt starts when it takes its turn to run
+type checkActivity struct {
+	mutex   sync.Mutex
+	nextID  int
+	running map[int]time.Time
+}
+
+func newCheckActivity() *checkActivity {
+	return &checkActivity{running: make(map[int]time.Time)}
+}
+
+// begin registers an attempt that started at the given time and returns its id, which is given to end when it finishes
+func (a *checkActivity) begin(now time.Time) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.nextID++
+	a.running[a.nextID] = now
+	return a.nextID
+}
+
+// end registers that the attempt finished
+func (a *checkActivity) end(id int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.running, id)
+}
+
+// oldest returns when the longest running attempt started, or the zero time when no check is running
+func (a *checkActivity) oldest() time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var oldest time.Time
+	for _, start := range a.running {
+		if oldest.IsZero() || start.Before(oldest) {
+			oldest = start
+		}
+	}
+	return oldest
+}
+
+func notifySystemd(state string) {
+	if err := systemd.Notify(state); err != nil {
+		logger.Warnf("Error notifying systemd of \"%v\". %v", state, err)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/systemd"
+)
+
+// listenNotifySocket stands in for the socket of the service manager, returning the states received
+func listenNotifySocket(t *testing.T) <-chan string {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets are not available. %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	previous, wasSet := os.LookupEnv("NOTIFY_SOCKET")
+	os.Setenv("NOTIFY_SOCKET", socket)
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv("NOTIFY_SOCKET", previous)
+		} else {
+			os.Unsetenv("NOTIFY_SOCKET")
+		}
+	})
+
+	states := make(chan string, 100)
+	go func() {
+		buffer := make([]byte, 256)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				return
+			}
+			states <- string(buffer[:n])
+		}
+	}()
+	return states
+}
+
+func TestNotifyWatchdogStopsWhileACheckIsStuck(t *testing.T) {
+	states := listenNotifySocket(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interval := 20 * time.Millisecond
+	checks := newCheckActivity()
+	go notifyWatchdog(ctx, interval, checks)
+
+	expectPing := func() {
+		t.Helper()
+		select {
+		case state := <-states:
+			if state != systemd.Watchdog {
+				t.Fatalf("expected %v, got %v", systemd.Watchdog, state)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the watchdog to be notified")
+		}
+	}
+
+	expectPing()
+
+	// a check that started longer than the timeout ago is stuck
+	stuck := checks.begin(time.Now().Add(-time.Minute))
+	time.Sleep(2 * interval)
+	drain(states)
+	select {
+	case state := <-states:
+		t.Fatalf("expected no notification while the check is stuck, got %v", state)
+	case <-time.After(5 * interval):
+	}
+
+	// a check that is running for less than the timeout is not stuck
+	checks.end(stuck)
+	running := checks.begin(time.Now())
+	expectPing()
+	checks.end(running)
+	expectPing()
+}
+
+func drain(states <-chan string) {
+	for {
+		select {
+		case <-states:
+		default:
+			return
+		}
+	}
+}
+
+func TestCheckActivityOldest(t *testing.T) {
+	checks := newCheckActivity()
+	if !checks.oldest().IsZero() {
+		t.Fatal("expected no check running")
+	}
+
+	start := time.Date(2021, 1, 21, 9, 0, 0, 0, time.UTC)
+	first := checks.begin(start)
+	second := checks.begin(start.Add(time.Minute))
+
+	if oldest := checks.oldest(); !oldest.Equal(start) {
+		t.Errorf("expected %v, got %v", start, oldest)
+	}
+
+	checks.end(first)
+	if oldest := checks.oldest(); !oldest.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected %v, got %v", start.Add(time.Minute), oldest)
+	}
+
+	checks.end(second)
+	if !checks.oldest().IsZero() {
+		t.Error("expected no check running")
+	}
+}
+
+func TestCheckIsTimedFromItsTurn(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	configuration := testConfiguration()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocked := scheduledCheck{name: "blocked", run: func(ctx context.Context, configuration config.Configuration) error {
+		close(started)
+		<-release
+		return nil
+	}}
+
+	// another check holds the turn, so the check waits without being timed
+	checkMutex.Lock()
+	finished := make(chan struct{})
+	go func() {
+		check(context.Background(), configuration, newConnectionMonitor(time.Hour), blocked)
+		close(finished)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if oldest := runningChecks.oldest(); !oldest.IsZero() {
+		checkMutex.Unlock()
+		close(release)
+		t.Fatalf("expected the check waiting for its turn not to be timed, it is timed since %v", oldest)
+	}
+
+	checkMutex.Unlock()
+	<-started
+	if oldest := runningChecks.oldest(); oldest.IsZero() {
+		t.Error("expected the check to be timed once it takes its turn")
+	}
+
+	close(release)
+	<-finished
+	if oldest := runningChecks.oldest(); !oldest.IsZero() {
+		t.Errorf("expected the finished check not to be timed, it is timed since %v", oldest)
+	}
+}
+
+func TestCheckIsNotTimedBetweenRetries(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	initialRetryDelay = 100 * time.Millisecond
+	failing := scheduledCheck{name: "failing", run: func(ctx context.Context, configuration config.Configuration) error {
+		return errors.New("query timeout")
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		check(ctx, testConfiguration(), newConnectionMonitor(time.Hour), failing)
+		close(finished)
+	}()
+
+	// the check waits 100ms after its first attempt
+	time.Sleep(30 * time.Millisecond)
+	if oldest := runningChecks.oldest(); !oldest.IsZero() {
+		t.Errorf("expected the check waiting to retry not to be timed, it is timed since %v", oldest)
+	}
+	cancel()
+	<-finished
+}
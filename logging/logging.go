@@ -0,0 +1,188 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+const (
+	// LevelDebug is used for details that are only needed when investigating a problem, such as the text of the queries
+	LevelDebug Level = iota
+	// LevelInfo is used for the normal operation of the program
+	LevelInfo
+	// LevelWarn is used for failures that the program recovers from
+	LevelWarn
+	// LevelError is used for failures that need attention
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns the level with the given name
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("Unknown log level \"%v\"", name)
+}
+
+var (
+	// mutex protects the settings and keeps the entries from being interleaved
+	mutex    sync.Mutex
+	output   io.Writer = os.Stderr
+	minLevel           = LevelInfo
+	useJSON  bool
+)
+
+// Open writes the log to the file at the given path, which is rotated once it grows past the configured size.
+// The rotated files are kept until Configure applies the limits of the configuration.
+// The entries of the standard logger, such as the ones of the libraries, are also written to it.
+func Open(path string) error {
+	file, err := openRotatingFile(path)
+	if err != nil {
+		return err
+	}
+
+	var defaults config.Log
+	file.maxSize = defaults.GetMaxSize()
+
+	mutex.Lock()
+	output = file
+	mutex.Unlock()
+
+	log.SetFlags(0)
+	log.SetOutput(standardWriter{})
+	return nil
+}
+
+// Configure applies the level, format, rotation and redaction settings of the configuration
+func Configure(logConfig config.Log) error {
+	level, err := ParseLevel(logConfig.GetLevel())
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := setRedactionPatterns(logConfig.Redact); err != nil {
+		return err
+	}
+
+	minLevel = level
+	useJSON = logConfig.GetFormat() == "json"
+	if file, ok := output.(*rotatingFile); ok {
+		file.configure(logConfig.GetMaxSize(), logConfig.GetMaxAge(), logConfig.GetMaxBackups())
+	}
+	return nil
+}
+
+// Logger writes the entries of a component of the program, such as the database or the notifier
+type Logger struct {
+	component string
+}
+
+// New creates a logger whose entries are identified by the given component
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// Debugf writes an entry with the debug level
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	write(LevelDebug, l.component, fmt.Sprintf(format, maskSensitive(v)...))
+}
+
+// Infof writes an entry with the info level
+func (l *Logger) Infof(format string, v ...interface{}) {
+	write(LevelInfo, l.component, fmt.Sprintf(format, maskSensitive(v)...))
+}
+
+// Warnf writes an entry with the warn level
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	write(LevelWarn, l.component, fmt.Sprintf(format, maskSensitive(v)...))
+}
+
+// Errorf writes an entry with the error level
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	write(LevelError, l.component, fmt.Sprintf(format, maskSensitive(v)...))
+}
+
+// Panic writes an entry with the error level and then panics with its message
+func (l *Logger) Panic(v ...interface{}) {
+	message := fmt.Sprint(maskSensitive(v)...)
+	write(LevelError, l.component, message)
+	panic(message)
+}
+
+// Fatal writes an entry with the error level and then exits the program
+func (l *Logger) Fatal(v ...interface{}) {
+	write(LevelError, l.component, fmt.Sprint(maskSensitive(v)...))
+	os.Exit(1)
+}
+
+// entry is the JSON representation of a log entry
+type entry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Component string `json:"component,omitempty"`
+	Message   string `json:"message"`
+}
+
+func write(level Level, component string, message string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if level < minLevel {
+		return
+	}
+
+	if redaction {
+		message = redact(message)
+	}
+
+	now := time.Now()
+	var line []byte
+	if useJSON {
+		line, _ = json.Marshal(entry{Time: now.Format(time.RFC3339), Level: level.String(), Component: component, Message: message})
+	} else {
+		text := now.Format("2006/01/02 15:04:05") + " " + strings.ToUpper(level.String())
+		if component != "" {
+			text += " [" + component + "]"
+		}
+		line = []byte(text + " " + message)
+	}
+
+	if _, err := output.Write(append(line, '\n')); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing to the log.", err)
+	}
+}
+
+// standardWriter writes the entries of the standard logger with the info level
+type standardWriter struct{}
+
+func (standardWriter) Write(p []byte) (int, error) {
+	write(LevelInfo, "", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
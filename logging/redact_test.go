@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog writes the log to a buffer for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+
+	mutex.Lock()
+	output = &buffer
+	mutex.Unlock()
+
+	t.Cleanup(func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		output = os.Stderr
+		redaction = true
+		secretValues = make(map[string]bool)
+		personalData = make(map[string]bool)
+		recentPersonalData = make(map[string]uint64)
+		personalDataPattern = nil
+	})
+	return &buffer
+}
+
+func TestRedaction(t *testing.T) {
+	buffer := captureLog(t)
+	logger := New("test")
+
+	AddSecret("s3cr3t!")
+	AddPersonalData("Maria Souza", "João Lima", "Ana", "")
+
+	tests := []struct {
+		name       string
+		log        func()
+		redacted   string
+		unredacted string
+	}{
+		{"ticket summary keeps the number and description", func() {
+			logger.Infof("    %v", "123456 [P1] Sistema fora do ar - Maria Souza (SLA 21/01 14:00)")
+		}, "123456 [P1] Sistema fora do ar - *** (SLA 21/01 14:00)", "123456 [P1] Sistema fora do ar - Maria Souza (SLA 21/01 14:00)"},
+		{"action url", func() {
+			logger.Infof("    %v: %v", "Abrir 123456", "https://João Lima:s3cr3t!@cherwell/incident/123456")
+		}, "Abrir 123456: https://***@cherwell/incident/123456", "Abrir 123456: https://João Lima:s3cr3t!@cherwell/incident/123456"},
+		{"e-mail keeps the domain", func() {
+			logger.Infof("Owner %v", "maria.souza@empresa.com.br")
+		}, "Owner ***@empresa.com.br", "Owner maria.souza@empresa.com.br"},
+		{"short values are not masked", func() {
+			logger.Infof("Analyst Ana")
+		}, "Analyst Ana", "Analyst Ana"},
+		{"sensitive arguments", func() {
+			logger.Debugf("Executing query with the arguments %v.", Sensitive("[team user]"))
+		}, "Executing query with the arguments ***.", "Executing query with the arguments [team user]."},
+		{"connection string", func() {
+			logger.Errorf("Error connecting with %v", "server=db;user id=sa;password=s3cr3t!;")
+		}, "Error connecting with server=db;user id=***;password=***;", "Error connecting with server=db;user id=sa;password=s3cr3t!;"},
+	}
+
+	minLevel = LevelDebug
+	defer func() { minLevel = LevelInfo }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, enabled := range []bool{true, false} {
+				SetRedaction(enabled)
+				buffer.Reset()
+				test.log()
+
+				expected := test.redacted
+				if !enabled {
+					expected = test.unredacted
+				}
+				if line := strings.TrimSpace(buffer.String()); !strings.HasSuffix(line, expected) {
+					t.Errorf("redaction %v: expected the entry to end with %q, got %q", enabled, expected, line)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactionMatchesWholeWords(t *testing.T) {
+	captureLog(t)
+	AddPersonalData("Lima", "Maria")
+	AddRecentPersonalData("Maria Souza", "José Luz")
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Lima", "***"},
+		{"Owner Lima.", "Owner ***."},
+		{"Limao, Limas and LimaLima", "Limao, Limas and LimaLima"},
+		{"Lima_2 and 2Lima", "Lima_2 and 2Lima"},
+		{"Açaí Lima and ÁLima", "Açaí *** and ÁLima"},
+		{"Maria Souza and Maria", "*** and ***"},
+		{"Mariana Lima", "Mariana ***"},
+		{"José Luzia and José Luz", "José Luzia and ***"},
+	}
+
+	for _, test := range tests {
+		if redacted := redact(test.message); redacted != test.expected {
+			t.Errorf("expected %q to be redacted as %q, got %q", test.message, test.expected, redacted)
+		}
+	}
+}
+
+func TestRecentPersonalDataIsLimited(t *testing.T) {
+	captureLog(t)
+	AddPersonalData("Maria Souza")
+
+	AddRecentPersonalData("Customer 0")
+	for i := 1; i < maxRecentPersonalData; i++ {
+		AddRecentPersonalData(fmt.Sprintf("Customer %v", i))
+		// the first customer is seen again on every check, so it remains one of the most recent
+		AddRecentPersonalData("Customer 0")
+	}
+	for i := maxRecentPersonalData; i < maxRecentPersonalData+100; i++ {
+		AddRecentPersonalData(fmt.Sprintf("Customer %v", i), "Customer 0")
+	}
+
+	if len(recentPersonalData) != maxRecentPersonalData {
+		t.Errorf("expected the personal data to be limited to %v values, got %v", maxRecentPersonalData, len(recentPersonalData))
+	}
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Customer 1", "Customer 1"},
+		{"Customer 100", "Customer 100"},
+		{"Customer 101", "***"},
+		{"Customer 1099", "***"},
+		{"Customer 0", "***"},
+		{"Maria Souza", "***"},
+	}
+	for _, test := range tests {
+		if redacted := redact(test.message); redacted != test.expected {
+			t.Errorf("expected %q to be redacted as %q, got %q", test.message, test.expected, redacted)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeLayout is appended to the name of the rotated files, which keeps them sorted by the time they were rotated
+const backupTimeLayout string = "2006-01-02T15-04-05.000"
+
+// rotatingFile is a log file that is renamed once it grows past maxSize, such as "log-2021-01-21T14-00-00.000.txt",
+// keeping at most maxBackups of the renamed files for at most maxAge. A zero value disables each limit,
+// which is how the value -1 of the configuration is given.
+type rotatingFile struct {
+	path       string
+	file       *os.File
+	size       int64
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	r := &rotatingFile{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// configure changes the limits and removes the rotated files beyond them
+func (r *rotatingFile) configure(maxSize int64, maxAge time.Duration, maxBackups int) {
+	r.maxSize = maxSize
+	r.maxAge = maxAge
+	r.maxBackups = maxBackups
+	r.removeOldBackups()
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = fileInfo.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error rotating the log file.", err)
+		}
+	}
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file and starts a new one. The file is closed first, since windows does not rename open files.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.path, r.backupName(time.Now())); err != nil {
+		return err
+	}
+	r.removeOldBackups()
+	return r.open()
+}
+
+func (r *rotatingFile) backupName(rotation time.Time) string {
+	extension := filepath.Ext(r.path)
+	return strings.TrimSuffix(r.path, extension) + "-" + rotation.Format(backupTimeLayout) + extension
+}
+
+// removeOldBackups removes the rotated files that are beyond maxBackups or older than maxAge
+func (r *rotatingFile) removeOldBackups() {
+	extension := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(r.path, extension) + "-"
+
+	matches, err := filepath.Glob(prefix + "*" + extension)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path     string
+		rotation time.Time
+	}
+	var backups []backup
+	for _, match := range matches {
+		rotation, err := time.ParseInLocation(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(match, prefix), extension), time.Local)
+		if err == nil {
+			backups = append(backups, backup{path: match, rotation: rotation})
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotation.After(backups[j].rotation)
+	})
+
+	for i, b := range backups {
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.maxAge > 0 && time.Since(b.rotation) > r.maxAge) {
+			if err := os.Remove(b.path); err != nil {
+				fmt.Fprintln(os.Stderr, "Error removing the rotated log file.", err)
+			}
+		}
+	}
+}
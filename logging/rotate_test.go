@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// createBackups creates rotated files of the log, one per day before the given time, and returns the path of the log
+func createBackups(t *testing.T, count int, now time.Time) string {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	r := &rotatingFile{path: path}
+	for i := 1; i <= count; i++ {
+		if err := ioutil.WriteFile(r.backupName(now.Add(-time.Duration(i)*24*time.Hour)), []byte("entry\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func countBackups(t *testing.T, path string) int {
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "log-*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(matches)
+}
+
+func TestOpenKeepsBackupsUntilConfigured(t *testing.T) {
+	path := createBackups(t, 8, time.Now())
+	t.Cleanup(func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if file, ok := output.(*rotatingFile); ok && file.file != nil {
+			file.file.Close()
+		}
+		output = os.Stderr
+	})
+
+	if err := Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if count := countBackups(t, path); count != 8 {
+		t.Fatalf("expected Open to keep the 8 rotated files, got %v", count)
+	}
+
+	if err := Configure(config.Log{MaxBackups: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if count := countBackups(t, path); count != 6 {
+		t.Errorf("expected Configure to keep 6 rotated files, got %v", count)
+	}
+}
+
+func TestRemoveOldBackups(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      config.Log
+		expected int
+	}{
+		{"defaults", config.Log{}, 5},
+		{"maxBackups", config.Log{MaxBackups: 3}, 3},
+		{"maxAgeDays", config.Log{MaxAgeDays: 2, MaxBackups: -1}, 2},
+		{"maxAgeDays and maxBackups", config.Log{MaxAgeDays: 4, MaxBackups: 2}, 2},
+		{"no limits", config.Log{MaxAgeDays: -1, MaxBackups: -1}, 40},
+		{"maxBackups disabled, default maxAgeDays", config.Log{MaxBackups: -1}, 30},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the backups are a bit more recent than whole days, so that maxAgeDays keeps the ones of the last days
+			path := createBackups(t, 40, time.Now().Add(time.Hour))
+			r := &rotatingFile{path: path}
+			r.configure(test.log.GetMaxSize(), test.log.GetMaxAge(), test.log.GetMaxBackups())
+
+			if count := countBackups(t, path); count != test.expected {
+				t.Errorf("expected %v rotated files, got %v", test.expected, count)
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	r, err := openRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.file.Close()
+	r.configure(10, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+		// the rotated files are named after the millisecond in which they were rotated
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if count := countBackups(t, path); count != 2 {
+		t.Errorf("expected 2 rotated files, got %v", count)
+	}
+
+	disabled := config.Log{MaxSizeMB: -1}
+	r.configure(disabled.GetMaxSize(), 0, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := countBackups(t, path); count != 2 {
+		t.Errorf("expected no rotation when maxSizeMB is -1, got %v rotated files", count)
+	}
+}
@@ -1,247 +1,572 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
-	"strings"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/getlantern/systray"
+	// embeds the time zone database, which is not available on windows, so that job.timezone can be used
+	_ "time/tzdata"
+
+	"github.com/pedroppinheiro/cwnotifier/assets"
+	"github.com/pedroppinheiro/cwnotifier/cherwell"
 	"github.com/pedroppinheiro/cwnotifier/config"
 	"github.com/pedroppinheiro/cwnotifier/database"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/logging"
 	"github.com/pedroppinheiro/cwnotifier/notifier"
-
-	"log"
+	"github.com/pedroppinheiro/cwnotifier/scheduler"
+	"github.com/pedroppinheiro/cwnotifier/sla"
+	"github.com/pedroppinheiro/cwnotifier/tracker"
 
 	_ "github.com/denisenkom/go-mssqldb"
 )
 
 const (
-	defaultYAMLName string = "config.yaml"
-	defaultLogName  string = "log.txt"
+	defaultYAMLName  string = "config.yaml"
+	defaultLogName   string = "log.txt"
+	defaultStateName string = "state.json"
+)
+
+// shutdownTimeout limits how long the program waits for the running checks when it is closing
+var shutdownTimeout = 10 * time.Second
+
+var (
+	logger = logging.New("main")
+	// schedulerLogger identifies the entries about the scheduled checks
+	schedulerLogger = logging.New("scheduler")
 )
 
 // Version will be defined in compile time.
 var version = "undefined"
 
-func init() {
-	// configuring log to file
-	logFile, err := os.OpenFile(defaultLogName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
-	if err != nil {
+// notificationTracker avoids notifying the same items on every check
+var notificationTracker *tracker.Tracker
+
+// dataSource provides the items that are checked by the notifications
+var dataSource datasource.DataSource
+
+// checkMutex makes the attempts of the scheduled checks run one at a time, since they share the data source, the connection monitor and the tracker.
+// It is not held while a check waits to retry.
+var checkMutex sync.Mutex
+
+// crashed is signalled by recoverFromError, so that run shuts the program down
+var crashed = make(chan struct{}, 1)
+
+// errCrashed is returned by run when it was shut down because of a panic, which was already notified to the user
+var errCrashed = errors.New("CWNotifier is closing due to errors")
+
+// runningChecks tracks the attempts of the checks in progress, so that the systemd watchdog is not notified while a check is stuck
+var runningChecks = newCheckActivity()
+
+func main() {
+	// configuring log to file. Its level, format and rotation are configured once the configuration is read.
+	// It is opened here instead of in init, so that the tests don't write to it.
+	if err := logging.Open(defaultLogName); err != nil {
 		panic(err)
 	}
-	log.SetOutput(logFile)
-}
 
-func main() {
-	log.Printf("CWNotifier is starting. Program version: %v", version)
-	systray.Run(onReady, nil)
-	log.Printf("CWNotifier has finished")
+	logger.Infof("CWNotifier is starting. Program version: %v", version)
+	exitCode := runCommand(os.Args[1:])
+	logger.Infof("CWNotifier has finished")
+	os.Exit(exitCode)
 }
 
-func onReady() {
-	defer recoverFromError()
+// run checks cherwell until the context is cancelled, such as when the user quits or the program receives a signal. The ready function is called once the checks are scheduled.
+// An error is returned when the program cannot start, such as when the configuration is invalid.
+func run(ctx context.Context, ready func()) error {
+	configuration, err := readConfiguration(configurationLocation)
+	if err != nil {
+		return err
+	}
+	if err := applySettings(configuration); err != nil {
+		return err
+	}
 
-	configureSystemtray()
+	if logUnredacted {
+		logger.Warnf("The log is not redacted, it may contain passwords, e-mails and names.")
+	}
 
-	configuration, err := readConfiguration(defaultYAMLName)
+	backend, err := notifier.New(configuration.Notifier)
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
+	notifier.SetNotifier(backend)
 
-	// used to maintain compatibility with previous versions in which the default was "SUSIS - GERIN"
-	if configuration.User.Team == "" {
-		configuration.User.Team = "SUSIS - GERIN"
-		log.Println("user.team is empty, using \"SUSIS - GERIN\" as fallback.")
+	if !configuration.IsNotificationsEnabled() {
+		notifier.NotifyNoNotificationsEnabled()
+		return nil
 	}
 
-	if !configuration.Notification.IsNotificationsEnabled() {
-		notifier.NotifyNoNotificationsEnabled()
-		systray.Quit()
-		return
+	notificationTracker, err = tracker.Load(defaultStateName, configuration.Job.GetEscalationInterval())
+	if err != nil {
+		return fmt.Errorf("Error reading the notification state file. %w", err)
 	}
 
-	database.Connect(configuration.Database)
-	defer database.CloseConnection()
+	monitor := newConnectionMonitor(configuration.Job.GetOutageThreshold())
+
+	dataSource, err = connectDataSource(ctx, configuration)
+	if err != nil {
+		logger.Errorf("%v", err)
+		monitor.failed(err, time.Now())
+	}
+	defer closeDataSource()
 
 	notifier.NotifyProgramStart()
-	for ; true; time.Sleep(time.Duration(configuration.Job.SleepMinutes) * time.Minute) {
-		shouldNotify, err := shouldCheckDatabase(time.Now(), configuration.Job)
-		if !shouldNotify || err != nil {
-			log.Println("Skipped checking cherwell. ", err)
-			continue
+	reloads := make(chan config.Configuration)
+	go watchConfiguration(ctx, configurationLocation, configurationPollInterval, reloads)
+
+	checks, err := startChecks(ctx, configuration, monitor, true)
+	if err != nil {
+		return err
+	}
+	ready()
+
+	for {
+		select {
+		case newConfiguration := <-reloads:
+			checks.Stop()
+			configuration = applyConfiguration(configuration, newConfiguration, monitor)
+			if checks, err = startChecks(ctx, configuration, monitor, false); err != nil {
+				return err
+			}
+		case <-crashed:
+			shutdown(checks)
+			return errCrashed
+		case <-ctx.Done():
+			shutdown(checks)
+			return nil
 		}
+	}
+}
 
-		if configuration.Notification.EnableIncidentsWithoutOwnerNotification {
-			notifyIncidentsWithoutOwnerNotification(configuration)
+// shutdown stops the checks, cancelling the queries in flight, and closes the data source.
+// A check that does not stop within shutdownTimeout is abandoned, so that the program is not kept from closing.
+func shutdown(checks *scheduler.Scheduler) {
+	logger.Infof("Shutting down (1/2): stopping the checks.")
+	stopped := make(chan struct{})
+	go func() {
+		checks.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		logger.Infof("Shutting down (1/2): the checks were stopped.")
+	case <-time.After(shutdownTimeout):
+		logger.Warnf("Shutting down (1/2): the checks did not stop within %v, abandoning them.", shutdownTimeout)
+	}
+
+	logger.Infof("Shutting down (2/2): closing the data source.")
+	closeDataSource()
+	logger.Infof("Shutting down: done.")
+}
+
+// stopOnSignal calls stop when the program is interrupted or terminated
+func stopOnSignal(stop context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		received := <-signals
+		logger.Infof("Received signal \"%v\", shutting down.", received)
+		stop()
+	}()
+}
+
+// scheduledCheck is a notification or rule that is checked on its own schedule
+type scheduledCheck struct {
+	name     string
+	schedule string
+	run      func(ctx context.Context, configuration config.Configuration) error
+}
+
+// scheduledChecks returns every enabled notification and rule of each profile, with its schedule
+func scheduledChecks(configuration config.Configuration) []scheduledCheck {
+	var checks []scheduledCheck
+	job := configuration.Job
+	schedules := configuration.Schedules
+
+	for _, profile := range configuration.GetProfiles() {
+		profile := profile
+		add := func(notificationType string, schedule string, notify func(context.Context, config.Configuration, config.Profile) error) {
+			checks = append(checks, scheduledCheck{profile.Kind(notificationType), schedule, func(ctx context.Context, configuration config.Configuration) error {
+				return notify(ctx, configuration, profile)
+			}})
+		}
+
+		if profile.Notification.EnableIncidentsWithoutOwnerNotification {
+			add("incidentsWithoutOwner", job.GetSchedule(schedules.IncidentsWithoutOwner), notifyIncidentsWithoutOwnerNotification)
 		}
 
-		if configuration.Notification.EnableTasksWithoutOwnerNotification {
-			notifyTasksWithoutOwnerNotification(configuration)
+		if profile.Notification.EnableTasksWithoutOwnerNotification {
+			add("tasksWithoutOwner", job.GetSchedule(schedules.TasksWithoutOwner), notifyTasksWithoutOwnerNotification)
 		}
 
-		if configuration.Notification.EnableIncidentsWithClosedTasksNotification {
-			notifyIncidentsWithClosedTasksNotification(configuration)
+		if profile.Notification.EnableIncidentsWithClosedTasksNotification {
+			add("incidentsWithClosedTasks", job.GetSchedule(schedules.IncidentsWithClosedTasks), notifyIncidentsWithClosedTasksNotification)
 		}
 
-		if configuration.Notification.EnableChangesThatNeedToBeValidatedNotification {
-			notifyChangesThatNeedToBeValidated(configuration)
+		if profile.Notification.EnableChangesThatNeedToBeValidatedNotification {
+			add("changesThatNeedToBeValidated", job.GetSchedule(schedules.ChangesThatNeedToBeValidated), notifyChangesThatNeedToBeValidated)
 		}
 
-		if configuration.Notification.EnableChangesThatRequireUpdateNotification {
-			notifyChangesThatRequireUpdate(configuration)
+		if profile.Notification.EnableChangesThatRequireUpdateNotification {
+			add("changesThatRequireUpdate", job.GetSchedule(schedules.ChangesThatRequireUpdate), notifyChangesThatRequireUpdate)
+		}
+
+		for _, rule := range configuration.Rules {
+			rule := rule
+			add("rule:"+rule.Name, rule.GetSchedule(job), func(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+				return notifyRule(ctx, configuration, profile, rule)
+			})
 		}
 	}
+
+	return checks
 }
 
-func notifyIncidentsWithoutOwnerNotification(configuration config.Configuration) {
-	incidents := database.GetIncidentsWithoutOwner(configuration.User.Team)
-	if len(incidents) >= 1 {
-		notifier.NotifyIncidentsWithoutOwner(strings.Join(incidents[:], ","))
+// startChecks schedules every enabled notification and rule. When runNow is true they are also checked immediately.
+// An error is returned when a schedule cannot be parsed, which the validation of the configuration should have prevented.
+func startChecks(ctx context.Context, configuration config.Configuration, monitor *connectionMonitor, runNow bool) (*scheduler.Scheduler, error) {
+	var tasks []scheduler.Task
+	for _, c := range scheduledChecks(configuration) {
+		c := c
+		schedule, err := config.ParseSchedule(c.schedule, configuration.Job.GetLocation())
+		if err != nil {
+			return nil, fmt.Errorf("Error scheduling %v at \"%v\". %w", c.name, c.schedule, err)
+		}
+
+		schedulerLogger.Infof("%v is scheduled to be checked at \"%v\".", c.name, c.schedule)
+		tasks = append(tasks, scheduler.Task{
+			Name:     c.name,
+			Schedule: schedule,
+			Run: func(ctx context.Context) {
+				defer recoverFromError()
+				check(ctx, configuration, monitor, c)
+			},
+		})
 	}
+	return scheduler.Start(ctx, tasks, runNow), nil
 }
 
-func notifyTasksWithoutOwnerNotification(configuration config.Configuration) {
-	tasks := database.GetTasksWithoutOwner(configuration.User.Team, configuration.User.Email)
-	if len(tasks) >= 1 {
-		notifier.NotifyTasksWithoutOwner(strings.Join(tasks[:], ","))
+// check checks cherwell for a notification or rule, if the current time is within the job's calendar, retrying when it fails.
+// The checks take turns on each attempt, so that a check waiting to retry does not hold back the others.
+func check(ctx context.Context, configuration config.Configuration, monitor *connectionMonitor, c scheduledCheck) {
+	if ctx.Err() != nil {
+		return
 	}
-}
 
-func notifyIncidentsWithClosedTasksNotification(configuration config.Configuration) {
-	incidents := database.GetIncidentsWithClosedTasks(configuration.User.Team, configuration.User.Name)
-	if len(incidents) >= 1 {
-		notifier.NotifyIncidentsWithClosedTasks(strings.Join(incidents[:], ","))
+	shouldNotify, err := shouldCheckDatabase(time.Now(), configuration)
+	if !shouldNotify || err != nil {
+		schedulerLogger.Infof("Skipped checking %v. %v", c.name, err)
+		return
 	}
-}
 
-func notifyChangesThatNeedToBeValidated(configuration config.Configuration) {
-	changes := database.GetChangesThatNeedToBeValidated(configuration.User.Name)
-	if len(changes) >= 1 {
-		notifier.NotifyChangesThatNeedToBeValidated(strings.Join(changes[:], ","))
+	err = retry(ctx, configuration.Job.GetRetries(), initialRetryDelay, func() error {
+		checkMutex.Lock()
+		defer checkMutex.Unlock()
+
+		// the attempt is timed from when it takes its turn, so the watchdog does not count the wait for the other checks nor between the attempts
+		id := runningChecks.begin(time.Now())
+		defer runningChecks.end(id)
+
+		return checkCherwell(ctx, configuration, c.run)
+	})
+
+	checkMutex.Lock()
+	defer checkMutex.Unlock()
+
+	if ctx.Err() != nil {
+		schedulerLogger.Infof("The check of %v was cancelled.", c.name)
+	} else if err != nil {
+		monitor.failed(err, time.Now())
+	} else {
+		monitor.succeeded(time.Now())
 	}
 }
 
-func notifyChangesThatRequireUpdate(configuration config.Configuration) {
-	changes := database.GetChangesThatRequireUpdate(configuration.User.Name)
-	if len(changes) >= 1 {
-		notifier.NotifyChangesThatRequireUpdate(strings.Join(changes[:], ","))
+// checkCherwell executes the check, connecting to the data source if needed.
+// When an error occurs the connection is closed, so that the next attempt connects again.
+func checkCherwell(ctx context.Context, configuration config.Configuration, run func(ctx context.Context, configuration config.Configuration) error) error {
+	if dataSource == nil {
+		var err error
+		if dataSource, err = connectDataSource(ctx, configuration); err != nil {
+			return err
+		}
 	}
+
+	err := run(ctx, configuration)
+	if err != nil {
+		closeDataSource()
+	}
+	return err
 }
 
-func recoverFromError() {
-	if r := recover(); r != nil {
-		notifier.NotifyError()
-		log.Fatal("CWNotifier is closing due to errors")
+func notifyIncidentsWithoutOwnerNotification(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+	incidents, err := dataSource.GetIncidentsWithoutOwner(ctx, profile.User.Team)
+	if err != nil {
+		return err
 	}
+
+	summaries, actions, escalation := dueTickets(profile.Kind("incidentsWithoutOwner"), incidents, respondDeadline, configuration.Portal.IncidentURL, configuration.SLA, time.Now())
+	if len(summaries) >= 1 {
+		notifier.NotifyIncidentsWithoutOwner(profile.GetName(), summaries, actions, escalation)
+	}
+	return nil
 }
 
-// https://dev.to/osuka42/building-a-simple-system-tray-app-with-go-899
-func configureSystemtray() {
-	systray.SetIcon(readFileContent("assets\\app.ico"))
-	systray.SetTitle("CWNotifier")
-	systray.SetTooltip("CWNotifier")
+func notifyTasksWithoutOwnerNotification(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+	tasks, err := dataSource.GetTasksWithoutOwner(ctx, profile.User.Team, profile.User.Email)
+	if err != nil {
+		return err
+	}
 
-	showLogMenuItem := systray.AddMenuItem("Show log", "Show the app's log")
-	showLogMenuItem.SetIcon(readFileContent("assets\\log.ico"))
-	go func() {
-		for {
-			<-showLogMenuItem.ClickedCh
-			cmd := exec.Command("notepad", defaultLogName)
-			if err := cmd.Run(); err != nil {
-				log.Println("An error occurred during show log menu action. ", err)
-			}
-		}
-	}()
+	summaries, actions, escalation := dueTickets(profile.Kind("tasksWithoutOwner"), tasks, resolveDeadline, configuration.Portal.IncidentURL, configuration.SLA, time.Now())
+	if len(summaries) >= 1 {
+		notifier.NotifyTasksWithoutOwner(profile.GetName(), summaries, actions, escalation)
+	}
+	return nil
+}
 
-	quitMenuItem := systray.AddMenuItem("Quit", "Quit the app")
-	quitMenuItem.SetIcon(readFileContent("assets\\quit.ico"))
-	go func() {
-		<-quitMenuItem.ClickedCh
-		log.Println("User requested to quit")
-		systray.Quit()
-	}()
+func notifyIncidentsWithClosedTasksNotification(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+	incidents, err := dataSource.GetIncidentsWithClosedTasks(ctx, profile.User.Team, profile.User.Name)
+	if err != nil {
+		return err
+	}
+
+	summaries, actions, escalation := dueTickets(profile.Kind("incidentsWithClosedTasks"), incidents, resolveDeadline, configuration.Portal.IncidentURL, configuration.SLA, time.Now())
+	if len(summaries) >= 1 {
+		notifier.NotifyIncidentsWithClosedTasks(profile.GetName(), summaries, actions, escalation)
+	}
+	return nil
 }
 
-func readConfiguration(yamlLocation string) (config.Configuration, error) {
-	yamlContent := readFileContent(yamlLocation)
-	return config.ReadConfiguration(yamlContent)
+func notifyChangesThatNeedToBeValidated(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+	changes, err := dataSource.GetChangesThatNeedToBeValidated(ctx, profile.User.Name)
+	if err != nil {
+		return err
+	}
+
+	summaries, actions, escalation := dueTickets(profile.Kind("changesThatNeedToBeValidated"), changes, resolveDeadline, configuration.Portal.ChangeURL, configuration.SLA, time.Now())
+	if len(summaries) >= 1 {
+		notifier.NotifyChangesThatNeedToBeValidated(profile.GetName(), summaries, actions, escalation)
+	}
+	return nil
 }
 
-func readFileContent(filePath string) []byte {
-	content, err := ioutil.ReadFile(filePath)
+func notifyChangesThatRequireUpdate(ctx context.Context, configuration config.Configuration, profile config.Profile) error {
+	changes, err := dataSource.GetChangesThatRequireUpdate(ctx, profile.User.Name)
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
-	return content
+
+	summaries, actions, escalation := dueTickets(profile.Kind("changesThatRequireUpdate"), changes, resolveDeadline, configuration.Portal.ChangeURL, configuration.SLA, time.Now())
+	if len(summaries) >= 1 {
+		notifier.NotifyChangesThatRequireUpdate(profile.GetName(), summaries, actions, escalation)
+	}
+	return nil
 }
 
-func shouldCheckDatabase(givenTime time.Time, jobConfig config.Job) (bool, error) {
+// ticketDeadline selects which SLA deadline of a ticket is checked by a notification
+type ticketDeadline func(ticket datasource.Ticket) time.Time
 
-	if isWeekend(givenTime) {
-		return false, errors.New("Current date is a weekend")
+// respondDeadline is used by the notifications of tickets that nobody picked up yet, so the response deadline is the one at risk
+func respondDeadline(ticket datasource.Ticket) time.Time {
+	if !ticket.SLARespondDeadline.IsZero() {
+		return ticket.SLARespondDeadline
 	}
+	return ticket.SLADeadline
+}
 
-	checkTimeString := fmt.Sprintf("%02d:%02d", givenTime.Hour(), givenTime.Minute()) // https://stackoverflow.com/a/51546906/1252947
-	isBetweenValidTime, err := inTimeSpan(jobConfig.Start, jobConfig.End, checkTimeString)
+func resolveDeadline(ticket datasource.Ticket) time.Time {
+	return ticket.SLADeadline
+}
 
-	if err != nil {
-		log.Panic("An unexpected error occurred during verification of the time to run the job. ", err)
+// slaSeverity maps the SLA level of a ticket to the severity of the notification
+func slaSeverity(level sla.Level) notifier.Severity {
+	switch level {
+	case sla.LevelNormal:
+		return notifier.SeverityWarning
+	case sla.LevelUrgent:
+		return notifier.SeverityUrgent
+	case sla.LevelCritical:
+		return notifier.SeverityCritical
 	}
+	return notifier.SeverityInfo
+}
 
-	if isBetweenValidTime {
-		return true, nil
+// dueTickets returns the summaries of the tickets that should be notified now, according to the notification tracker,
+// the actions that open them in the cherwell portal, when the url template is given,
+// and the severity to which the notification should be escalated, according to the SLA of the tickets.
+// A ticket whose SLA level increased is notified immediately.
+func dueTickets(notificationType string, tickets []datasource.Ticket, deadline ticketDeadline, urlTemplate string, slaConfig config.SLA, now time.Time) ([]string, []notifier.Action, notifier.Severity) {
+	statuses := make([]sla.Status, len(tickets))
+	items := make([]tracker.Item, len(tickets))
+	for i, ticket := range tickets {
+		priority, _ := strconv.Atoi(ticket.Priority)
+		statuses[i] = sla.Evaluate(ticket.CreatedAt, deadline(ticket), now, slaConfig.GetThreshold(priority))
+		items[i] = tracker.Item{Key: ticket.Number, Level: int(statuses[i].Level)}
 	}
 
-	return false, errors.New("Current time is not between valid work time range")
-}
+	due := make(map[string]bool)
+	for _, number := range notificationTracker.Due(notificationType, items, now) {
+		due[number] = true
+	}
 
-// isWeekend returns true if the given time is a weekend, otherwise returns false
-func isWeekend(givenTime time.Time) bool {
-	isSaturday := givenTime.Weekday() == time.Saturday
-	isSunday := givenTime.Weekday() == time.Sunday
+	var (
+		summaries []string
+		actions   []notifier.Action
+	)
+	escalation := notifier.SeverityInfo
+	for i, ticket := range tickets {
+		if !due[ticket.Number] {
+			continue
+		}
+		delete(due, ticket.Number)
 
-	return isSaturday || isSunday
+		summary := ticket.Summary()
+		if status := statuses[i].String(); status != "" {
+			summary += " - " + status
+		}
+		summaries = append(summaries, summary)
+
+		if urlTemplate != "" {
+			actions = append(actions, notifier.Action{Label: "Abrir " + ticket.Number, URL: config.TicketURL(urlTemplate, ticket.Number)})
+		}
+
+		if severity := slaSeverity(statuses[i].Level); severity > escalation {
+			escalation = severity
+		}
+	}
+	return summaries, actions, escalation
 }
 
-// inTimeSpan returns true if a "check" time is between a "start" and an "end" range.
-// Parameters must be given in the form of "hh:mm"
-// https://stackoverflow.com/a/55093788/1252947
-func inTimeSpan(start, end, check string) (bool, error) {
-	if !config.IsValidTime(start) {
-		return false, fmt.Errorf("Invalid time given: \"%v\". Should be in the form of hh:mm", start)
+// connectDataSource connects to the data source, it is replaced by the tests
+var connectDataSource = connect
+
+// connect connects to the configured data source
+func connect(ctx context.Context, configuration config.Configuration) (datasource.DataSource, error) {
+	switch configuration.GetDataSource() {
+	case config.RESTDataSource:
+		client, err := cherwell.Connect(ctx, configuration.Cherwell, configuration.Job.GetLocation())
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case config.FixtureDataSource:
+		fixture, err := datasource.ReadFixture(configuration.Fixture)
+		if err != nil {
+			return nil, err
+		}
+		return fixture, nil
 	}
 
-	if !config.IsValidTime(end) {
-		return false, fmt.Errorf("Invalid time given: \"%v\". Should be in the form of hh:mm", end)
+	sqlServer, err := database.Connect(ctx, configuration.Database, configuration.Schema)
+	if err != nil {
+		return nil, err
 	}
+	return sqlServer, nil
+}
+
+// closeDataSource closes the data source, if there is one
+func closeDataSource() {
+	if dataSource != nil {
+		dataSource.Close()
+		dataSource = nil
+	}
+}
 
-	if !config.IsValidTime(check) {
-		return false, fmt.Errorf("Invalid time given: \"%v\". Should be in the form of hh:mm", check)
+// notifyRule checks a user defined rule for a profile
+func notifyRule(ctx context.Context, configuration config.Configuration, profile config.Profile, rule config.Rule) error {
+	executor, ok := dataSource.(datasource.RuleExecutor)
+	if !ok {
+		return fmt.Errorf("The data source \"%v\" does not support rules", configuration.GetDataSource())
 	}
 
-	newLayout := "15:04"
-	startTime, err := time.Parse(newLayout, start)
+	items, err := executor.ExecuteRule(ctx, rule, profile.User)
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	items = notificationTracker.Due(profile.Kind("rule:"+rule.Name), tracker.Items(items), time.Now())
+	if len(items) >= 1 {
+		notifier.NotifyRule(profile.GetName(), rule.Title, rule.Message, items)
+	}
+	return nil
+}
+
+// recoverFromError recovers from a panic, notifying the user and making run shut the program down,
+// so that the checks are stopped and the data source is closed before the program exits
+func recoverFromError() {
+	if r := recover(); r != nil {
+		notifier.NotifyError()
+		logger.Errorf("%v\n%s", r, debug.Stack())
+		select {
+		case crashed <- struct{}{}:
+		default:
+		}
 	}
-	endTime, err := time.Parse(newLayout, end)
+}
+
+// readConfiguration reads and validates the configuration file. It changes nothing in the running program,
+// the settings that are kept outside of the configuration are applied by applySettings.
+func readConfiguration(yamlLocation string) (config.Configuration, error) {
+	yamlContent, err := ioutil.ReadFile(yamlLocation)
 	if err != nil {
-		return false, err
+		return config.Configuration{}, err
 	}
-	checkTime, err := time.Parse(newLayout, check)
+
+	configuration, err := config.ReadConfiguration(yamlContent)
 	if err != nil {
-		return false, err
+		return config.Configuration{}, err
+	}
+
+	// used to maintain compatibility with previous versions in which the default was "SUSIS - GERIN"
+	if len(configuration.Profiles) == 0 && configuration.User.Team == "" {
+		configuration.User.Team = "SUSIS - GERIN"
+		logger.Warnf("user.team is empty, using \"SUSIS - GERIN\" as fallback.")
+	}
+
+	return configuration, nil
+}
+
+// applySettings applies the settings of the configuration that are kept by other packages:
+// the log, the assets directory and the secrets that are masked in the log
+func applySettings(configuration config.Configuration) error {
+	if err := logging.Configure(configuration.Log); err != nil {
+		return err
 	}
+	assets.SetDirectory(configuration.Assets.Directory)
 
-	if startTime.Before(endTime) {
-		return !checkTime.Before(startTime) && !checkTime.After(endTime), nil
+	logging.SetRedaction(!logUnredacted)
+	for _, secret := range configuration.Secrets() {
+		logging.AddSecret(secret)
 	}
-	if startTime.Equal(endTime) {
-		return checkTime.Equal(startTime), nil
+	logging.AddPersonalData(configuration.PersonalData()...)
+	return nil
+}
+
+// shouldCheckDatabase returns true if the given time is within the calendar of the job.
+// Otherwise the returned error explains why and tells when the next check will be.
+func shouldCheckDatabase(givenTime time.Time, configuration config.Configuration) (bool, error) {
+	isWorkingTime, reason := configuration.Calendar.IsWorkingTime(givenTime, configuration.Job)
+	if isWorkingTime {
+		return true, nil
+	}
+
+	next, hasNext := configuration.Calendar.NextWorkingTime(givenTime, configuration.Job)
+	if !hasNext {
+		return false, fmt.Errorf("%v. There is no working time in the calendar for the next year", reason)
 	}
-	return !startTime.After(checkTime) || !endTime.Before(checkTime), nil
+
+	return false, fmt.Errorf("%v. Next check at %v", reason, next.Format("2006-01-02 15:04 MST"))
 }
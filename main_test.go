@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+	"github.com/pedroppinheiro/cwnotifier/sla"
+	"github.com/pedroppinheiro/cwnotifier/tracker"
+)
+
+// useMemory makes the checks read the given data source and record the notifications, restoring the previous state at the end of the test
+func useMemory(t *testing.T, memory *datasource.Memory, escalationInterval time.Duration) *notifier.Recorder {
+	previousDataSource, previousConnect, previousTracker, previousDelay := dataSource, connectDataSource, notificationTracker, initialRetryDelay
+	t.Cleanup(func() {
+		dataSource, connectDataSource, notificationTracker, initialRetryDelay = previousDataSource, previousConnect, previousTracker, previousDelay
+		notifier.SetNotifier(nil)
+	})
+
+	recorder := &notifier.Recorder{}
+	notifier.SetNotifier(recorder)
+	dataSource = memory
+	connectDataSource = func(ctx context.Context, configuration config.Configuration) (datasource.DataSource, error) {
+		return memory, nil
+	}
+	notificationTracker = tracker.New(escalationInterval)
+	initialRetryDelay = time.Millisecond
+	return recorder
+}
+
+// testConfiguration returns a configuration whose calendar allows checking at any time
+func testConfiguration(profiles ...config.Profile) config.Configuration {
+	weekdays := make(map[string][]string)
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		weekdays[day] = []string{"00:00-23:59"}
+	}
+
+	return config.Configuration{
+		Calendar: config.Calendar{Weekdays: weekdays},
+		Job:      config.Job{SleepMinutes: 1},
+		Profiles: profiles,
+	}
+}
+
+// runChecks checks every enabled notification and rule once, as the scheduler does
+func runChecks(configuration config.Configuration, monitor *connectionMonitor) {
+	for _, c := range scheduledChecks(configuration) {
+		check(context.Background(), configuration, monitor, c)
+	}
+}
+
+// itemNumbers returns the ticket numbers at the start of the items of a notification
+func itemNumbers(notification notifier.Notification) []string {
+	var numbers []string
+	for _, item := range notification.Items {
+		numbers = append(numbers, strings.Fields(item)[0])
+	}
+	return numbers
+}
+
+func TestCheckNotifiesOnlyNewItems(t *testing.T) {
+	memory := &datasource.Memory{IncidentsWithoutOwner: []datasource.Ticket{{Number: "100", Priority: "1"}}}
+	recorder := useMemory(t, memory, time.Hour)
+	configuration := testConfiguration(config.Profile{
+		User:         config.User{Team: "Support"},
+		Notification: config.Notification{EnableIncidentsWithoutOwnerNotification: true},
+	})
+	monitor := newConnectionMonitor(time.Minute)
+
+	runChecks(configuration, monitor)
+	runChecks(configuration, monitor)
+	if notifications := recorder.Notifications(); len(notifications) != 1 {
+		t.Fatalf("expected the item to be notified once, got %v notifications", len(notifications))
+	}
+
+	memory.IncidentsWithoutOwner = append(memory.IncidentsWithoutOwner, datasource.Ticket{Number: "200", Priority: "2"})
+	runChecks(configuration, monitor)
+
+	notifications := recorder.Notifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected the new item to be notified, got %v notifications", len(notifications))
+	}
+	if numbers := itemNumbers(notifications[1]); !reflect.DeepEqual(numbers, []string{"200"}) {
+		t.Errorf("expected only the new item to be notified, got %v", numbers)
+	}
+}
+
+func TestDueTicketsRealertsAfterEscalationInterval(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, 15*time.Minute)
+	tickets := []datasource.Ticket{{Number: "100"}}
+	now := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		elapsed  time.Duration
+		expected int
+	}{
+		{0, 1},
+		{time.Minute, 0},
+		{14 * time.Minute, 0},
+		{15 * time.Minute, 1},
+		{20 * time.Minute, 0},
+		{30 * time.Minute, 1},
+	}
+
+	for _, test := range tests {
+		summaries, _, _ := dueTickets("incidentsWithoutOwner", tickets, respondDeadline, "", config.SLA{}, now.Add(test.elapsed))
+		if len(summaries) != test.expected {
+			t.Errorf("after %v expected %v notified items, got %v", test.elapsed, test.expected, summaries)
+		}
+	}
+}
+
+func TestDueTicketsRealertsWhenSLALevelIncreases(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, 24*time.Hour)
+	createdAt := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+	tickets := []datasource.Ticket{{Number: "100", Priority: "1", CreatedAt: createdAt, SLARespondDeadline: createdAt.Add(100 * time.Minute)}}
+
+	tests := []struct {
+		elapsed  time.Duration
+		expected int
+		severity notifier.Severity
+	}{
+		{0, 1, notifier.SeverityInfo},
+		{10 * time.Minute, 0, notifier.SeverityInfo},
+		{50 * time.Minute, 1, notifier.SeverityWarning},
+		{60 * time.Minute, 0, notifier.SeverityInfo},
+		{80 * time.Minute, 1, notifier.SeverityUrgent},
+		{90 * time.Minute, 0, notifier.SeverityInfo},
+		{100 * time.Minute, 1, notifier.SeverityCritical},
+		{110 * time.Minute, 0, notifier.SeverityInfo},
+	}
+
+	for _, test := range tests {
+		summaries, actions, severity := dueTickets("incidentsWithoutOwner", tickets, respondDeadline, "https://portal/{{number}}", config.SLA{}, createdAt.Add(test.elapsed))
+		if len(summaries) != test.expected {
+			t.Errorf("after %v expected %v notified items, got %v", test.elapsed, test.expected, summaries)
+		}
+		if len(actions) != test.expected {
+			t.Errorf("after %v expected %v actions, got %v", test.elapsed, test.expected, actions)
+		}
+		if severity != test.severity {
+			t.Errorf("after %v expected severity %v, got %v", test.elapsed, test.severity, severity)
+		}
+	}
+}
+
+func TestCheckNotifiesEachProfile(t *testing.T) {
+	memory := &datasource.Memory{
+		IncidentsWithoutOwner: []datasource.Ticket{
+			{Number: "100", Team: "Support"},
+			{Number: "200", Team: "Network"},
+			{Number: "300"},
+		},
+		TasksWithoutOwner: []datasource.Ticket{
+			{Number: "400", Owner: "ana@example.com"},
+			{Number: "500", Team: "Network"},
+		},
+	}
+	recorder := useMemory(t, memory, time.Hour)
+	notification := config.Notification{EnableIncidentsWithoutOwnerNotification: true, EnableTasksWithoutOwnerNotification: true}
+	configuration := testConfiguration(
+		config.Profile{User: config.User{Team: "Support", Email: "ana@example.com"}, Notification: notification},
+		config.Profile{Name: "Redes", User: config.User{Team: "Network", Email: "bob@example.com"}, Notification: notification},
+	)
+
+	runChecks(configuration, newConnectionMonitor(time.Minute))
+
+	notified := make(map[string][]string)
+	for _, n := range recorder.Notifications() {
+		notified[n.Title] = itemNumbers(n)
+	}
+	expected := map[string][]string{
+		"Aviso de chamado prioritário sem responsável - Support": {"100", "300"},
+		"Aviso de tarefa prioritária sem responsável - Support":  {"400"},
+		"Aviso de chamado prioritário sem responsável - Redes":   {"200", "300"},
+		"Aviso de tarefa prioritária sem responsável - Redes":    {"500"},
+	}
+	if !reflect.DeepEqual(notified, expected) {
+		t.Errorf("expected the notifications %v, got %v", expected, notified)
+	}
+
+	// each profile keeps its own notified items, so the ticket without team is not notified again to either of them
+	for _, kind := range []string{"Support/incidentsWithoutOwner", "Redes/incidentsWithoutOwner"} {
+		if due := notificationTracker.Due(kind, tracker.Items([]string{"300"}), time.Now()); len(due) != 0 {
+			t.Errorf("expected %v to have notified the ticket already, got %v due", kind, due)
+		}
+	}
+	if due := notificationTracker.Due("incidentsWithoutOwner", tracker.Items([]string{"300"}), time.Now()); len(due) != 1 {
+		t.Errorf("expected the notification type without profile to be tracked apart, got %v due", due)
+	}
+}
+
+func TestCheckReportsErrorsToConnectionMonitor(t *testing.T) {
+	memory := &datasource.Memory{Err: errors.New("connection lost")}
+	recorder := useMemory(t, memory, time.Hour)
+	configuration := testConfiguration(config.Profile{
+		User:         config.User{Team: "Support"},
+		Notification: config.Notification{EnableIncidentsWithoutOwnerNotification: true},
+	})
+	monitor := newConnectionMonitor(0)
+
+	runChecks(configuration, monitor)
+	if monitor.outageStart.IsZero() || !monitor.outageNotified {
+		t.Fatalf("expected the error to start a notified outage, got %+v", monitor)
+	}
+	notifications := recorder.Notifications()
+	if len(notifications) != 1 || notifications[0].Title != "Sem conexão com o cherwell" {
+		t.Fatalf("expected the outage to be notified, got %+v", notifications)
+	}
+
+	memory.Err = nil
+	runChecks(configuration, monitor)
+	if !monitor.outageStart.IsZero() {
+		t.Errorf("expected the outage to end, got %+v", monitor)
+	}
+	notifications = recorder.Notifications()
+	if len(notifications) != 2 || notifications[1].Title != "Conexão com o cherwell restabelecida" {
+		t.Errorf("expected the restored connection to be notified, got %+v", notifications)
+	}
+}
+
+func TestSLASeverity(t *testing.T) {
+	expected := map[sla.Level]notifier.Severity{
+		sla.LevelNone:     notifier.SeverityInfo,
+		sla.LevelNormal:   notifier.SeverityWarning,
+		sla.LevelUrgent:   notifier.SeverityUrgent,
+		sla.LevelCritical: notifier.SeverityCritical,
+	}
+
+	for level, severity := range expected {
+		if s := slaSeverity(level); s != severity {
+			t.Errorf("expected level %v to be notified as %v, got %v", level, severity, s)
+		}
+	}
+}
+
+func TestStartChecksInvalidSchedule(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	configuration := testConfiguration(config.Profile{
+		User:         config.User{Team: "Support"},
+		Notification: config.Notification{EnableIncidentsWithoutOwnerNotification: true},
+	})
+	configuration.Schedules.IncidentsWithoutOwner = "invalid"
+
+	checks, err := startChecks(context.Background(), configuration, newConnectionMonitor(time.Minute), false)
+	if err == nil {
+		checks.Stop()
+		t.Fatal("expected the invalid schedule to be reported")
+	}
+	if !strings.Contains(err.Error(), "Support/incidentsWithoutOwner") {
+		t.Errorf("expected the error to name the check, got %v", err)
+	}
+}
+
+func TestCheckRetryingDoesNotHoldBackOtherChecks(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	initialRetryDelay = 100 * time.Millisecond
+	configuration := testConfiguration()
+	monitor := newConnectionMonitor(time.Hour)
+
+	failing := scheduledCheck{name: "failing", run: func(ctx context.Context, configuration config.Configuration) error {
+		return errors.New("query timeout")
+	}}
+	var succeeded int
+	succeeding := scheduledCheck{name: "succeeding", run: func(ctx context.Context, configuration config.Configuration) error {
+		succeeded++
+		return nil
+	}}
+
+	finished := make(chan struct{})
+	go func() {
+		check(context.Background(), configuration, monitor, failing)
+		close(finished)
+	}()
+
+	// the failing check waits 100ms, 200ms and 400ms between its attempts
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	check(context.Background(), configuration, monitor, succeeding)
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond || succeeded != 1 {
+		t.Errorf("expected the check to run while the other waits to retry, it took %v", elapsed)
+	}
+
+	<-finished
+	if monitor.outageStart.IsZero() {
+		t.Error("expected the failing check to be reported to the connection monitor")
+	}
+}
@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// maxToastActions is the maximum number of buttons supported by windows notifications
+const maxToastActions int = 5
+
+// Action is a button shown in the notification that opens the given URL when clicked
+type Action struct {
+	Label string
+	URL   string
+}
+
+// Invoke opens the action's URL in the default application
+func (a Action) Invoke() error {
+	return openURL(a.URL)
+}
+
+// toastActions returns the url opened by clicking the windows notification itself, which is the one of the first action,
+// and the actions shown as buttons, limited to the number supported by windows
+func toastActions(actions []Action) (string, []Action) {
+	if len(actions) == 0 {
+		return "", nil
+	}
+
+	if len(actions) > maxToastActions {
+		actions = actions[:maxToastActions]
+	}
+	return actions[0].URL, actions
+}
+
+// OpenFile opens the file in the default application of the platform, such as the log shown by the tray menu
+func OpenFile(location string) error {
+	absolute, err := filepath.Abs(location)
+	if err != nil {
+		return err
+	}
+	return openURL(absolute)
+}
+
+// openURL opens the url in the default application. It is replaced in the tests, which must not open a browser.
+var openURL = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestActionInvoke(t *testing.T) {
+	opened := recordOpenedURLs(t)
+
+	if err := (Action{Label: "Abrir 123", URL: "https://portal/123"}).Invoke(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url := <-opened; url != "https://portal/123" {
+		t.Errorf("expected the url of the action to be opened, got %v", url)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	opened := recordOpenedURLs(t)
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := OpenFile("log.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if location, expected := <-opened, filepath.Join(workingDirectory, "log.txt"); location != expected {
+		t.Errorf("expected %v to be opened, got %v", expected, location)
+	}
+}
+
+func TestToastActions(t *testing.T) {
+	actions := func(count int) []Action {
+		var result []Action
+		for i := 1; i <= count; i++ {
+			result = append(result, Action{Label: fmt.Sprintf("Abrir %v", i), URL: fmt.Sprintf("https://portal/%v", i)})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		actions    []Action
+		activation string
+		expected   []Action
+	}{
+		{"none", nil, "", nil},
+		{"one", actions(1), "https://portal/1", actions(1)},
+		{"limit", actions(maxToastActions), "https://portal/1", actions(maxToastActions)},
+		{"above limit", actions(maxToastActions + 3), "https://portal/1", actions(maxToastActions)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			activation, shown := toastActions(test.actions)
+			if activation != test.activation {
+				t.Errorf("expected the notification to open %q, got %q", test.activation, activation)
+			}
+			if !reflect.DeepEqual(shown, test.expected) {
+				t.Errorf("expected the buttons %v, got %v", test.expected, shown)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package notifier
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Names defined by the desktop notifications specification.
+// See https://specifications.freedesktop.org/notification-spec/latest/
+const (
+	dbusNotificationsDestination string          = "org.freedesktop.Notifications"
+	dbusNotificationsPath        dbus.ObjectPath = "/org/freedesktop/Notifications"
+	dbusNotificationsInterface   string          = "org.freedesktop.Notifications"
+
+	dbusDefaultAction string = "default"
+
+	dbusUrgencyLow      byte = 0
+	dbusUrgencyNormal   byte = 1
+	dbusUrgencyCritical byte = 2
+)
+
+// DBusNotifier emits desktop notifications through the freedesktop notifications D-Bus service,
+// which is provided by most linux desktop environments
+type DBusNotifier struct {
+	conn *dbus.Conn
+	icon string
+
+	mutex sync.Mutex
+	// actions holds the actions of the notifications that are still being displayed, by notification id
+	actions map[uint32][]Action
+}
+
+// NewDBusNotifier creates a notifier that sends the notifications through the given connection.
+// The connection is usually the session bus, but any bus that provides the notifications service can be used.
+func NewDBusNotifier(conn *dbus.Conn, icon string) (*DBusNotifier, error) {
+	d := &DBusNotifier{
+		conn:    conn,
+		icon:    icon,
+		actions: make(map[uint32][]Action),
+	}
+
+	err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(dbusNotificationsPath),
+		dbus.WithMatchInterface(dbusNotificationsInterface),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go d.handleSignals(signals)
+
+	return d, nil
+}
+
+func newSessionBusNotifier(icon string) (Notifier, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+	return NewDBusNotifier(conn, icon)
+}
+
+// Notify sends the notification to the notifications service
+func (d *DBusNotifier) Notify(notification Notification) error {
+	var actions []string
+	for i, action := range notification.Actions {
+		actions = append(actions, strconv.Itoa(i), action.Label)
+	}
+	if len(notification.Actions) > 0 {
+		// the default action is invoked when the notification itself is clicked
+		actions = append(actions, dbusDefaultAction, "")
+	}
+
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(dbusUrgency(notification.Severity)),
+	}
+
+	var id uint32
+	err := d.conn.Object(dbusNotificationsDestination, dbusNotificationsPath).Call(
+		dbusNotificationsInterface+".Notify", 0,
+		"CWNotifier",        // app_name
+		uint32(0),           // replaces_id
+		d.icon,              // app_icon
+		notification.Title,  // summary
+		notification.Body(), // body
+		actions,
+		hints,
+		int32(-1), // expire_timeout, -1 lets the server decide
+	).Store(&id)
+	if err != nil {
+		return err
+	}
+
+	if len(notification.Actions) > 0 {
+		d.mutex.Lock()
+		d.actions[id] = notification.Actions
+		d.mutex.Unlock()
+	}
+
+	return nil
+}
+
+// handleSignals executes the action chosen by the user and forgets the actions of closed notifications
+func (d *DBusNotifier) handleSignals(signals <-chan *dbus.Signal) {
+	for signal := range signals {
+		if len(signal.Body) < 2 {
+			continue
+		}
+
+		id, ok := signal.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+
+		switch signal.Name {
+		case dbusNotificationsInterface + ".ActionInvoked":
+			key, _ := signal.Body[1].(string)
+			d.invoke(id, key)
+		case dbusNotificationsInterface + ".NotificationClosed":
+			d.mutex.Lock()
+			delete(d.actions, id)
+			d.mutex.Unlock()
+		}
+	}
+}
+
+func (d *DBusNotifier) invoke(id uint32, key string) {
+	d.mutex.Lock()
+	actions := d.actions[id]
+	d.mutex.Unlock()
+
+	if key == dbusDefaultAction {
+		key = "0"
+	}
+
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || index >= len(actions) {
+		return
+	}
+
+	if err := actions[index].Invoke(); err != nil {
+		logger.Errorf("An error occurred during notification action. %v", err)
+	}
+}
+
+// dbusUrgency maps the notification severity to the urgency levels of the notifications specification
+func dbusUrgency(severity Severity) byte {
+	switch severity {
+	case SeverityInfo:
+		return dbusUrgencyLow
+	case SeverityUrgent, SeverityCritical:
+		return dbusUrgencyCritical
+	}
+	return dbusUrgencyNormal
+}
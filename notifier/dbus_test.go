@@ -0,0 +1,244 @@
+package notifier
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// busConfiguration is a minimal configuration for a private bus, which allows every connection to own names and receive signals
+const busConfiguration = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%DIR%</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startBus starts a private bus that stands in for the session bus, skipping the test when dbus-daemon is not installed
+func startBus(t *testing.T) string {
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not available")
+	}
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "bus.conf")
+	if err := ioutil.WriteFile(configFile, []byte(strings.Replace(busConfiguration, "%DIR%", dir, 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configFile, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Error reading the bus address. %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *dbus.Conn {
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// notifyCall holds the arguments of a Notify call received by fakeNotificationServer
+type notifyCall struct {
+	summary string
+	body    string
+	actions []string
+	hints   map[string]dbus.Variant
+}
+
+// fakeNotificationServer stands in for the org.freedesktop.Notifications service of the desktop environment
+type fakeNotificationServer struct {
+	mutex sync.Mutex
+	calls []notifyCall
+}
+
+func (f *fakeNotificationServer) Notify(appName string, replacesID uint32, appIcon string, summary string, body string,
+	actions []string, hints map[string]dbus.Variant, expireTimeout int32) (uint32, *dbus.Error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.calls = append(f.calls, notifyCall{summary: summary, body: body, actions: actions, hints: hints})
+	return uint32(len(f.calls)), nil
+}
+
+func (f *fakeNotificationServer) lastCall() notifyCall {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.calls[len(f.calls)-1]
+}
+
+// startNotificationServer exports a fakeNotificationServer on the bus and returns a DBusNotifier connected to it
+func startNotificationServer(t *testing.T) (*fakeNotificationServer, *dbus.Conn, *DBusNotifier) {
+	address := startBus(t)
+
+	serverConn := connectBus(t, address)
+	server := &fakeNotificationServer{}
+	if err := serverConn.Export(server, dbusNotificationsPath, dbusNotificationsInterface); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := serverConn.RequestName(dbusNotificationsDestination, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("Error owning %v: %v %v", dbusNotificationsDestination, reply, err)
+	}
+
+	d, err := NewDBusNotifier(connectBus(t, address), "icon.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, serverConn, d
+}
+
+// recordOpenedURLs replaces openURL for the duration of the test, sending the opened urls to the returned channel
+func recordOpenedURLs(t *testing.T) <-chan string {
+	opened := make(chan string, 10)
+	previous := openURL
+	openURL = func(url string) error {
+		opened <- url
+		return nil
+	}
+	t.Cleanup(func() { openURL = previous })
+	return opened
+}
+
+func TestDBusNotifierUrgency(t *testing.T) {
+	server, _, d := startNotificationServer(t)
+
+	tests := []struct {
+		severity Severity
+		urgency  byte
+	}{
+		{SeverityInfo, dbusUrgencyLow},
+		{SeverityWarning, dbusUrgencyNormal},
+		{SeverityUrgent, dbusUrgencyCritical},
+		{SeverityCritical, dbusUrgencyCritical},
+	}
+
+	for _, test := range tests {
+		if err := d.Notify(Notification{Title: "title", Message: "message", Severity: test.severity}); err != nil {
+			t.Fatalf("%v: %v", test.severity, err)
+		}
+
+		call := server.lastCall()
+		urgency, ok := call.hints["urgency"].Value().(byte)
+		if !ok || urgency != test.urgency {
+			t.Errorf("%v: expected urgency %v, got %v", test.severity, test.urgency, call.hints["urgency"])
+		}
+	}
+}
+
+func TestDBusNotifierActions(t *testing.T) {
+	server, _, d := startNotificationServer(t)
+
+	notification := Notification{
+		Title:   "title",
+		Message: "message",
+		Items:   []string{"123", "456"},
+		Actions: []Action{{Label: "Abrir 123", URL: "https://cherwell/123"}, {Label: "Abrir 456", URL: "https://cherwell/456"}},
+	}
+	if err := d.Notify(notification); err != nil {
+		t.Fatal(err)
+	}
+
+	call := server.lastCall()
+	expected := []string{"0", "Abrir 123", "1", "Abrir 456", dbusDefaultAction, ""}
+	if !reflect.DeepEqual(call.actions, expected) {
+		t.Errorf("expected actions %v, got %v", expected, call.actions)
+	}
+	if call.summary != "title" || call.body != "message\n123\n456" {
+		t.Errorf("unexpected summary %q and body %q", call.summary, call.body)
+	}
+
+	if err := d.Notify(Notification{Title: "without actions"}); err != nil {
+		t.Fatal(err)
+	}
+	if actions := server.lastCall().actions; len(actions) != 0 {
+		t.Errorf("expected no actions, got %v", actions)
+	}
+}
+
+func TestDBusNotifierActionInvoked(t *testing.T) {
+	opened := recordOpenedURLs(t)
+	_, serverConn, d := startNotificationServer(t)
+
+	actions := []Action{{Label: "Abrir 123", URL: "https://cherwell/123"}, {Label: "Abrir 456", URL: "https://cherwell/456"}}
+	if err := d.Notify(Notification{Title: "first", Actions: actions}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Notify(Notification{Title: "second", Actions: actions[:1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	emit := func(member string, id uint32, values ...interface{}) {
+		t.Helper()
+		if err := serverConn.Emit(dbusNotificationsPath, dbusNotificationsInterface+"."+member, append([]interface{}{id}, values...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectOpened := func(url string) {
+		t.Helper()
+		select {
+		case got := <-opened:
+			if got != url {
+				t.Errorf("expected %v to be opened, got %v", url, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %v to be opened", url)
+		}
+	}
+
+	emit("ActionInvoked", 1, "1")
+	expectOpened("https://cherwell/456")
+
+	// clicking the notification itself opens the first action
+	emit("ActionInvoked", 2, dbusDefaultAction)
+	expectOpened("https://cherwell/123")
+
+	// the actions of closed notifications, unknown notifications and unknown keys are ignored
+	emit("NotificationClosed", 1, uint32(2))
+	emit("ActionInvoked", 1, "0")
+	emit("ActionInvoked", 3, "0")
+	emit("ActionInvoked", 2, "5")
+	emit("ActionInvoked", 2, "0")
+	expectOpened("https://cherwell/123")
+
+	select {
+	case url := <-opened:
+		t.Errorf("unexpected url opened %v", url)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// EmailNotifier sends the notifications by email through an SMTP server.
+// The connection is upgraded with STARTTLS when the server supports it.
+type EmailNotifier struct {
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+// NewEmailNotifier creates a notifier that sends the notifications through the configured SMTP server.
+// The server is only authenticated when a user is given.
+func NewEmailNotifier(emailConfig config.Email) *EmailNotifier {
+	e := &EmailNotifier{
+		address: net.JoinHostPort(emailConfig.Host, strconv.Itoa(emailConfig.Port)),
+		from:    emailConfig.From,
+		to:      emailConfig.To,
+	}
+
+	if emailConfig.User != "" {
+		e.auth = smtp.PlainAuth("", emailConfig.User, emailConfig.Password, emailConfig.Host)
+	}
+
+	return e
+}
+
+// Notify sends the notification by email
+func (e *EmailNotifier) Notify(notification Notification) error {
+	var body strings.Builder
+	body.WriteString(notification.Body())
+	for _, action := range notification.Actions {
+		fmt.Fprintf(&body, "\n%v: %v", action.Label, action.URL)
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %v\r\n", e.from)
+	fmt.Fprintf(&message, "To: %v\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&message, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", "["+notification.Severity.String()+"] "+notification.Title))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.Replace(body.String(), "\n", "\r\n", -1))
+	message.WriteString("\r\n")
+
+	if err := smtp.SendMail(e.address, e.auth, e.from, e.to, []byte(message.String())); err != nil {
+		return fmt.Errorf("Error sending notification by email. %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package notifier
+
+import (
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// smtpSession is what a client sent to the fake SMTP server
+type smtpSession struct {
+	auth       string
+	from       string
+	recipients []string
+	data       string
+}
+
+// startSMTPServer stands in for an SMTP server without TLS, which accepts PLAIN authentication.
+// The recipients given in reject are refused. The session of each message received is sent to the returned channel.
+func startSMTPServer(t *testing.T, reject ...string) (int, <-chan smtpSession) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(textproto.NewConn(conn), reject, sessions)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, sessions
+}
+
+func serveSMTP(conn *textproto.Conn, reject []string, sessions chan<- smtpSession) {
+	defer conn.Close()
+	var session smtpSession
+
+	conn.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " ")[0])
+
+		switch command {
+		case "EHLO":
+			conn.PrintfLine("250-localhost")
+			conn.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+			session.auth = string(credentials)
+			conn.PrintfLine("235 Authenticated")
+		case "MAIL":
+			session.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			recipient := strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>")
+			rejected := false
+			for _, r := range reject {
+				rejected = rejected || r == recipient
+			}
+			if rejected {
+				conn.PrintfLine("550 No such user")
+				continue
+			}
+			session.recipients = append(session.recipients, recipient)
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 Go ahead")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			session.data = string(data)
+			sessions <- session
+			conn.PrintfLine("250 Queued")
+		case "QUIT":
+			conn.PrintfLine("221 Bye")
+			return
+		default:
+			conn.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestEmailNotify(t *testing.T) {
+	port, sessions := startSMTPServer(t)
+	email := NewEmailNotifier(config.Email{
+		Host:     "127.0.0.1",
+		Port:     port,
+		User:     "cwnotifier",
+		Password: "secret",
+		From:     "cwnotifier@example.com",
+		To:       []string{"ana@example.com", "bob@example.com"},
+	})
+
+	err := email.Notify(Notification{
+		Title:    "Aviso de chamado prioritário sem responsável",
+		Message:  "Existem chamados sem responsável",
+		Items:    []string{"100 [P1] Sem acesso"},
+		Severity: SeverityCritical,
+		Actions:  []Action{{Label: "Abrir 100", URL: "https://cherwell/incident/100"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	session := <-sessions
+	if session.auth != "\x00cwnotifier\x00secret" {
+		t.Errorf("expected the configured user to be authenticated, got %q", session.auth)
+	}
+	if session.from != "cwnotifier@example.com" || !reflect.DeepEqual(session.recipients, []string{"ana@example.com", "bob@example.com"}) {
+		t.Errorf("expected the message from the sender to each recipient, got %v to %v", session.from, session.recipients)
+	}
+
+	header, body := session.data, ""
+	if i := strings.Index(session.data, "\n\n"); i >= 0 {
+		header, body = session.data[:i], session.data[i+2:]
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(header, "\n") {
+		if i := strings.Index(line, ": "); i >= 0 {
+			fields[line[:i]] = line[i+2:]
+		}
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(fields["Subject"]); err != nil || subject != "[critical] Aviso de chamado prioritário sem responsável" {
+		t.Errorf("expected the subject to have the severity and the title, got %q", fields["Subject"])
+	}
+	expected := map[string]string{
+		"From":         "cwnotifier@example.com",
+		"To":           "ana@example.com, bob@example.com",
+		"Content-Type": "text/plain; charset=utf-8",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("expected the header %v: %v, got %q", name, value, fields[name])
+		}
+	}
+	if expected := "Existem chamados sem responsável\n100 [P1] Sem acesso\nAbrir 100: https://cherwell/incident/100\n"; body != expected {
+		t.Errorf("expected the body %q, got %q", expected, body)
+	}
+}
+
+func TestEmailNotifyWithoutUser(t *testing.T) {
+	port, sessions := startSMTPServer(t)
+	email := NewEmailNotifier(config.Email{Host: "127.0.0.1", Port: port, From: "cwnotifier@example.com", To: []string{"ana@example.com"}})
+
+	if err := email.Notify(Notification{Title: "CWNotifier started!"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session := <-sessions; session.auth != "" {
+		t.Errorf("expected no authentication without a user, got %q", session.auth)
+	}
+}
+
+func TestEmailNotifyRejected(t *testing.T) {
+	port, _ := startSMTPServer(t, "nobody@example.com")
+	email := NewEmailNotifier(config.Email{Host: "127.0.0.1", Port: port, From: "cwnotifier@example.com", To: []string{"nobody@example.com"}})
+
+	if err := email.Notify(Notification{Title: "Erro!"}); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("expected the rejected recipient to be reported, got %v", err)
+	}
+}
+
+func TestNewEmailNotifierAddress(t *testing.T) {
+	tests := map[string]string{
+		"smtp.example.com": "smtp.example.com:587",
+		"::1":              "[::1]:587",
+	}
+
+	for host, expected := range tests {
+		if address := NewEmailNotifier(config.Email{Host: host, Port: 587}).address; address != expected {
+			t.Errorf("expected %v, got %v", expected, address)
+		}
+	}
+}
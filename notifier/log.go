@@ -0,0 +1,16 @@
+package notifier
+
+// LogNotifier writes the notifications to the program's log instead of showing them to the user
+type LogNotifier struct{}
+
+// Notify writes the notification to the log
+func (LogNotifier) Notify(notification Notification) error {
+	logger.Infof("[%v] %v: %v", notification.Severity, notification.Title, notification.Message)
+	for _, item := range notification.Items {
+		logger.Infof("    %v", item)
+	}
+	for _, action := range notification.Actions {
+		logger.Infof("    %v: %v", action.Label, action.URL)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package notifier
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	incidentsWithoutOwnerNotificationTitle   string = "Aviso de chamado prioritário sem responsável"
+	incidentsWithoutOwnerNotificationMessage string = "Há chamados no backlog que demandam sua atenção urgente!"
+
+	tasksWithoutOwnerNotificationTitle   string = "Aviso de tarefa prioritária sem responsável"
+	tasksWithoutOwnerNotificationMessage string = "Há tarefas no backlog que demandam sua atenção urgente!"
+
+	incidentsWithClosedTasksNotificationTitle   string = "Aviso de chamado prioritário apto a encerrar"
+	incidentsWithClosedTasksNotificationMessage string = "Há chamados prioritários que já podem ser encerrados!"
+
+	changesThatNeedToBeValidatedNotificationTitle   string = "Aviso de mudança que precisa ser validada"
+	changesThatNeedToBeValidatedNotificationMessage string = "Há mudanças que foram resolvidas e já podem ser validadas!"
+
+	changesThatRequireUpdateNotificationTitle   string = "Aviso de mudança pendente de atualização"
+	changesThatRequireUpdateNotificationMessage string = "Há mudanças que estão pendentes de atualização para poderem ser aprovadas!"
+
+	noNotificationsEnabledTitle   string = "Nenhum notificação habilitada"
+	noNotificationsEnabledMessage string = "O programa está encerrando pois nenhuma notificação está habilitada. Por favor habilite no arquivo de configuração"
+
+	errorNotificationTitle   string = "Erro!"
+	errorNotificationMessage string = "Um erro ocorreu durante a execução e o programa foi encerrado. Verifique o arquivo de log."
+
+	invalidConfigurationTitle   string = "Configuração inválida"
+	invalidConfigurationMessage string = "O arquivo de configuração foi alterado, mas é inválido. A configuração anterior continua em uso. Verifique o arquivo de log."
+
+	outageNotificationTitle   string = "Sem conexão com o cherwell"
+	outageNotificationMessage string = "Não foi possível consultar o cherwell desde as %v. As notificações serão retomadas quando a conexão voltar. Verifique o arquivo de log."
+
+	connectionRestoredNotificationTitle   string = "Conexão com o cherwell restabelecida"
+	connectionRestoredNotificationMessage string = "A conexão com o cherwell voltou e as notificações foram retomadas."
+
+	programStartNotificationTitle   string = "CWNotifier started!"
+	programStartNotificationMessage string = "CWNotifier has started running."
+)
+
+var (
+	// current is the notifier used by the Notify functions. When it is not set, toast notifications are used.
+	current      Notifier
+	currentMutex sync.RWMutex
+)
+
+// SetNotifier changes the notifier used by the Notify functions
+func SetNotifier(n Notifier) {
+	currentMutex.Lock()
+	defer currentMutex.Unlock()
+
+	current = n
+}
+
+func push(notification Notification) {
+	currentMutex.RLock()
+	n := current
+	currentMutex.RUnlock()
+
+	if n == nil {
+		n = newToastNotifier(iconLocation())
+	}
+
+	err := n.Notify(notification)
+	if err != nil {
+		logger.Errorf("Error emitting notification. %v", err)
+	}
+}
+
+// maxSeverity returns the most urgent of the given severities
+func maxSeverity(a Severity, b Severity) Severity {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// profileTitle labels the title with the profile whose items are notified, when there is one
+func profileTitle(title string, profile string) string {
+	if profile == "" {
+		return title
+	}
+	return title + " - " + profile
+}
+
+// NotifyIncidentsWithoutOwner emits the notification about a priority cherwell's incident.
+// The profile labels the notification with the team it came from. The actions open the incidents in the cherwell portal and the escalation raises the severity of the notification,
+// such as when an SLA is about to be breached
+func NotifyIncidentsWithoutOwner(profile string, incidents []string, actions []Action, escalation Severity) {
+	push(Notification{
+		Title:    profileTitle(incidentsWithoutOwnerNotificationTitle, profile),
+		Message:  incidentsWithoutOwnerNotificationMessage,
+		Items:    incidents,
+		Severity: maxSeverity(SeverityUrgent, escalation),
+		Actions:  actions,
+	})
+
+	logger.Infof("incidentsWithoutOwnerNotification emitted.")
+}
+
+// NotifyTasksWithoutOwner emits the notification about a priority cherwell's incident
+func NotifyTasksWithoutOwner(profile string, tasks []string, actions []Action, escalation Severity) {
+	push(Notification{
+		Title:    profileTitle(tasksWithoutOwnerNotificationTitle, profile),
+		Message:  tasksWithoutOwnerNotificationMessage,
+		Items:    tasks,
+		Severity: maxSeverity(SeverityUrgent, escalation),
+		Actions:  actions,
+	})
+
+	logger.Infof("tasksWithoutOwnerNotification emitted.")
+}
+
+// NotifyIncidentsWithClosedTasks emits the notification about a priority cherwell's incident
+func NotifyIncidentsWithClosedTasks(profile string, incidents []string, actions []Action, escalation Severity) {
+	push(Notification{
+		Title:    profileTitle(incidentsWithClosedTasksNotificationTitle, profile),
+		Message:  incidentsWithClosedTasksNotificationMessage,
+		Items:    incidents,
+		Severity: maxSeverity(SeverityWarning, escalation),
+		Actions:  actions,
+	})
+
+	logger.Infof("incidentsWithClosedTasksNotification emitted.")
+}
+
+// NotifyChangesThatNeedToBeValidated emits the notification about a change that has been resolved and can be validated
+func NotifyChangesThatNeedToBeValidated(profile string, changes []string, actions []Action, escalation Severity) {
+	push(Notification{
+		Title:    profileTitle(changesThatNeedToBeValidatedNotificationTitle, profile),
+		Message:  changesThatNeedToBeValidatedNotificationMessage,
+		Items:    changes,
+		Severity: maxSeverity(SeverityWarning, escalation),
+		Actions:  actions,
+	})
+
+	logger.Infof("changesThatNeedToBeValidatedNotification emitted.")
+}
+
+// NotifyChangesThatRequireUpdate emits the notification about a change that require update
+func NotifyChangesThatRequireUpdate(profile string, changes []string, actions []Action, escalation Severity) {
+	push(Notification{
+		Title:    profileTitle(changesThatRequireUpdateNotificationTitle, profile),
+		Message:  changesThatRequireUpdateNotificationMessage,
+		Items:    changes,
+		Severity: maxSeverity(SeverityWarning, escalation),
+		Actions:  actions,
+	})
+
+	logger.Infof("changesThatRequireUpdateNotification emitted.")
+}
+
+// NotifyRule emits the notification of a user defined rule
+func NotifyRule(profile string, title string, message string, items []string) {
+	push(Notification{
+		Title:    profileTitle(title, profile),
+		Message:  message,
+		Items:    items,
+		Severity: SeverityWarning,
+	})
+
+	logger.Infof("Rule notification \"%v\" emitted.", title)
+}
+
+// NotifyProgramStart emits the notification about the start of the program
+func NotifyProgramStart() {
+	push(Notification{
+		Title:    programStartNotificationTitle,
+		Message:  programStartNotificationMessage,
+		Severity: SeverityInfo,
+	})
+
+	logger.Infof("Program start notification emitted.")
+}
+
+// NotifyError emits the notification about an error that occurred in the program
+func NotifyError() {
+	push(Notification{
+		Title:    errorNotificationTitle,
+		Message:  errorNotificationMessage,
+		Severity: SeverityUrgent,
+	})
+
+	logger.Infof("Error notification emitted.")
+}
+
+// NotifyOutage emits the notification about cherwell being unreachable since the given time
+func NotifyOutage(since time.Time) {
+	push(Notification{
+		Title:    outageNotificationTitle,
+		Message:  fmt.Sprintf(outageNotificationMessage, since.Format("15:04")),
+		Severity: SeverityUrgent,
+	})
+
+	logger.Infof("Outage notification emitted.")
+}
+
+// NotifyConnectionRestored emits the notification about the connection with cherwell being restored after an outage
+func NotifyConnectionRestored() {
+	push(Notification{
+		Title:    connectionRestoredNotificationTitle,
+		Message:  connectionRestoredNotificationMessage,
+		Severity: SeverityInfo,
+	})
+
+	logger.Infof("Connection restored notification emitted.")
+}
+
+// NotifyInvalidConfiguration emits the notification about a change in the configuration file that could not be applied
+func NotifyInvalidConfiguration() {
+	push(Notification{
+		Title:    invalidConfigurationTitle,
+		Message:  invalidConfigurationMessage,
+		Severity: SeverityWarning,
+	})
+
+	logger.Infof("Invalid configuration notification emitted.")
+}
+
+// NotifyNoNotificationsEnabled emits the notification about being no notifications enabled
+func NotifyNoNotificationsEnabled() {
+	push(Notification{
+		Title:    noNotificationsEnabledTitle,
+		Message:  noNotificationsEnabledMessage,
+		Severity: SeverityWarning,
+	})
+
+	logger.Infof("No notifications enabled notification emitted.")
+}
@@ -1,230 +1,133 @@
 package notifier
 
 import (
-	"log"
-	"unicode/utf8"
+	"fmt"
+	"strings"
 
-	"gopkg.in/toast.v1"
-
-	"os"
-	"path/filepath"
+	"github.com/pedroppinheiro/cwnotifier/assets"
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/logging"
 )
 
 const (
-	cherwellLogoName string = "assets\\cherwell.png"
-
-	incidentsWithoutOwnerNotificationTitle   string = "Aviso de chamado prioritário sem responsável"
-	incidentsWithoutOwnerNotificationMessage string = "Há chamados no backlog que demandam sua atenção urgente!"
-
-	tasksWithoutOwnerNotificationTitle   string = "Aviso de tarefa prioritária sem responsável"
-	tasksWithoutOwnerNotificationMessage string = "Há tarefas no backlog que demandam sua atenção urgente!"
-
-	incidentsWithClosedTasksNotificationTitle   string = "Aviso de chamado prioritário apto a encerrar"
-	incidentsWithClosedTasksNotificationMessage string = "Há chamados prioritários que já podem ser encerrados!"
-
-	changesThatNeedToBeValidatedNotificationTitle   string = "Aviso de mudança que precisa ser validada"
-	changesThatNeedToBeValidatedNotificationMessage string = "Há mudanças que foram resolvidas e já podem ser validadas!"
-
-	changesThatRequireUpdateNotificationTitle   string = "Aviso de mudança pendente de atualização"
-	changesThatRequireUpdateNotificationMessage string = "Há mudanças que estão pendentes de atualização para poderem ser aprovadas!"
-
-	noNotificationsEnabledTitle   string = "Nenhum notificação habilitada"
-	noNotificationsEnabledMessage string = "O programa está encerrando pois nenhuma notificação está habilitada. Por favor habilite no arquivo de configuração"
-
-	errorNotificationTitle   string = "Erro!"
-	errorNotificationMessage string = "Um erro ocorreu durante a execução e o programa foi encerrado. Verifique o arquivo de log."
-
-	programStartNotificationTitle   string = "CWNotifier started!"
-	programStartNotificationMessage string = "CWNotifier has started running."
+	// ToastBackend emits windows toast notifications
+	ToastBackend string = "toast"
+	// LogBackend writes the notifications to the program's log
+	LogBackend string = "log"
+	// DBusBackend emits linux desktop notifications through D-Bus
+	DBusBackend string = "dbus"
+	// WebhookBackend posts the notifications as JSON to an url
+	WebhookBackend string = "webhook"
+	// EmailBackend sends the notifications by email
+	EmailBackend string = "email"
 )
 
-var incidentsWithoutOwnerNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(incidentsWithoutOwnerNotificationTitle),
-	Message:  utf8toASCII(incidentsWithoutOwnerNotificationMessage),
-	Duration: "short",
-}
-
-var tasksWithoutOwnerNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(tasksWithoutOwnerNotificationTitle),
-	Message:  utf8toASCII(tasksWithoutOwnerNotificationMessage),
-	Duration: "short",
-}
-
-var incidentsWithClosedTasksNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(incidentsWithClosedTasksNotificationTitle),
-	Message:  utf8toASCII(incidentsWithClosedTasksNotificationMessage),
-	Duration: "short",
-}
-
-var changesThatNeedToBeValidatedNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(changesThatNeedToBeValidatedNotificationTitle),
-	Message:  utf8toASCII(changesThatNeedToBeValidatedNotificationMessage),
-	Duration: "short",
-}
-
-var changesThatRequireUpdateNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(changesThatRequireUpdateNotificationTitle),
-	Message:  utf8toASCII(changesThatRequireUpdateNotificationMessage),
-	Duration: "short",
-}
-
-var startNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(programStartNotificationTitle),
-	Message:  utf8toASCII(programStartNotificationMessage),
-	Duration: "short",
-}
-
-var errorNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(errorNotificationTitle),
-	Message:  utf8toASCII(errorNotificationMessage),
-	Duration: "short",
-}
+// Severity indicates how urgent a notification is
+type Severity int
 
-var noNotificationsEnabledNotification toast.Notification = toast.Notification{
-	AppID:    "CWNotifier",
-	Title:    utf8toASCII(noNotificationsEnabledTitle),
-	Message:  utf8toASCII(noNotificationsEnabledMessage),
-	Duration: "short",
-}
-
-func init() {
-	// get the absolute path of the cherwell logo image to then present it in the notification
-	currentDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
-	if err != nil {
-		log.Panic(err)
-	}
-
-	cherwellLogoLocation := currentDir + "\\" + cherwellLogoName
+const (
+	// SeverityInfo is used for informative notifications, such as the program start
+	SeverityInfo Severity = iota
+	// SeverityWarning is used for notifications that require attention, but are not urgent
+	SeverityWarning
+	// SeverityUrgent is used for notifications that require immediate attention
+	SeverityUrgent
+	// SeverityCritical is used for notifications about SLAs that were breached
+	SeverityCritical
+)
 
-	if fileExists(cherwellLogoLocation) {
-		incidentsWithoutOwnerNotification.Icon = cherwellLogoLocation
-		tasksWithoutOwnerNotification.Icon = cherwellLogoLocation
-		incidentsWithClosedTasksNotification.Icon = cherwellLogoLocation
-		changesThatNeedToBeValidatedNotification.Icon = cherwellLogoLocation
-		changesThatRequireUpdateNotification.Icon = cherwellLogoLocation
-	} else {
-		log.Printf("File \"%v\" was not found.\n", cherwellLogoLocation)
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityUrgent:
+		return "urgent"
+	case SeverityCritical:
+		return "critical"
 	}
+	return fmt.Sprintf("Severity(%d)", int(s))
 }
 
-// NotifyIncidentsWithoutOwner emits the windows notification about a priority cherwell's incident
-func NotifyIncidentsWithoutOwner(incidents string) {
-	incidentsWithoutOwnerNotification.Message = utf8toASCII(incidentsWithoutOwnerNotificationMessage) + "\n" + incidents
-	err := incidentsWithoutOwnerNotification.Push()
-	if err != nil {
-		log.Panic(err)
-	}
-
-	log.Printf("incidentsWithoutOwnerNotification emitted.")
+// Notification is the content sent to a notification backend
+type Notification struct {
+	Title    string
+	Message  string
+	Items    []string
+	Severity Severity
+	Actions  []Action
 }
 
-// NotifyTasksWithoutOwner emits the windows notification about a priority cherwell's incident
-func NotifyTasksWithoutOwner(tasks string) {
-	tasksWithoutOwnerNotification.Message = utf8toASCII(tasksWithoutOwnerNotificationMessage) + "\n" + tasks
-	err := tasksWithoutOwnerNotification.Push()
-	if err != nil {
-		log.Panic(err)
+// Body returns the message followed by the items, in the way it should be presented to the user
+func (n Notification) Body() string {
+	if len(n.Items) == 0 {
+		return n.Message
 	}
-
-	log.Printf("tasksWithoutOwnerNotification emitted.")
+	return n.Message + "\n" + strings.Join(n.Items, "\n")
 }
 
-// NotifyIncidentsWithClosedTasks emits the windows notification about a priority cherwell's incident
-func NotifyIncidentsWithClosedTasks(incidents string) {
-	incidentsWithClosedTasksNotification.Message = utf8toASCII(incidentsWithClosedTasksNotificationMessage) + "\n" + incidents
-	err := incidentsWithClosedTasksNotification.Push()
-	if err != nil {
-		log.Panic(err)
-	}
-
-	log.Printf("incidentsWithClosedTasksNotification emitted.")
+// Notifier is implemented by every notification backend
+type Notifier interface {
+	Notify(notification Notification) error
 }
 
-// NotifyChangesThatNeedToBeValidated emits the windows notification about a change that has been resolved and can be validated
-func NotifyChangesThatNeedToBeValidated(changes string) {
-	changesThatNeedToBeValidatedNotification.Message = utf8toASCII(changesThatNeedToBeValidatedNotificationMessage) + "\n" + changes
-	err := changesThatNeedToBeValidatedNotification.Push()
-	if err != nil {
-		log.Panic(err)
-	}
-
-	log.Printf("changesThatNeedToBeValidatedNotification emitted.")
-}
+// multiNotifier forwards a notification to several backends
+type multiNotifier []Notifier
 
-// NotifyChangesThatRequireUpdate emits the windows notification about a change that require update
-func NotifyChangesThatRequireUpdate(changes string) {
-	changesThatRequireUpdateNotification.Message = utf8toASCII(changesThatRequireUpdateNotificationMessage) + "\n" + changes
-	err := changesThatRequireUpdateNotification.Push()
-	if err != nil {
-		log.Panic(err)
+func (m multiNotifier) Notify(notification Notification) error {
+	var errs []string
+	for _, n := range m {
+		if err := n.Notify(notification); err != nil {
+			errs = append(errs, err.Error())
+		}
 	}
 
-	log.Printf("changesThatRequireUpdateNotification emitted.")
-}
-
-// NotifyProgramStart emits the windows notification about the start of the program
-func NotifyProgramStart() {
-	err := startNotification.Push()
-	if err != nil {
-		log.Panic(err)
+	if len(errs) > 0 {
+		return fmt.Errorf("Error emitting notification \"%v\": %v", notification.Title, strings.Join(errs, "; "))
 	}
-
-	log.Printf("Program start notification emitted.")
-}
-
-// NotifyError emits the windows notification about an error that occurred in the program
-func NotifyError() {
-	err := errorNotification.Push()
-	if err != nil {
-		log.Panic(err)
+	return nil
+}
+
+// New creates the notifier for the backends given in the configuration.
+// When more than one backend is configured every notification is sent to all of them.
+func New(notifierConfig config.Notifier) (Notifier, error) {
+	var notifiers multiNotifier
+	for _, backend := range notifierConfig.GetBackends() {
+		switch backend {
+		case ToastBackend:
+			notifiers = append(notifiers, newToastNotifier(iconLocation()))
+		case LogBackend:
+			notifiers = append(notifiers, LogNotifier{})
+		case DBusBackend:
+			dbusNotifier, err := newSessionBusNotifier(iconLocation())
+			if err != nil {
+				return nil, fmt.Errorf("Error connecting to the D-Bus session bus. %v", err)
+			}
+			notifiers = append(notifiers, dbusNotifier)
+		case WebhookBackend:
+			notifiers = append(notifiers, NewWebhookNotifier(notifierConfig.Webhook))
+		case EmailBackend:
+			notifiers = append(notifiers, NewEmailNotifier(notifierConfig.Email))
+		default:
+			return nil, fmt.Errorf("Unknown notification backend \"%v\"", backend)
+		}
 	}
 
-	log.Printf("Error notification emitted.")
-}
-
-// NotifyNoNotificationsEnabled emits the windows notification about being no notifications enabled
-func NotifyNoNotificationsEnabled() {
-	err := noNotificationsEnabledNotification.Push()
-	if err != nil {
-		log.Panic(err)
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
 	}
-
-	log.Printf("No notifications enabled notification emitted.")
+	return notifiers, nil
 }
 
-// fileExists checks if a file exists and is not a directory before we
-// try using it to prevent further errors. Code from: https://golangcode.com/check-if-a-file-exists/
-func fileExists(filename string) bool {
-	fileInfo, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
-	}
-
-	fileIsDir := fileInfo.IsDir()
-	if fileIsDir {
-		log.Printf("\"%v\" found, but is a directory.\n", cherwellLogoName)
-		return false
-	}
+var logger = logging.New("notifier")
 
-	return true
-}
-
-// utf8toASCII converts a UTF-8 internal string representation to standard
-// ASCII bytes. Code from: https://gist.github.com/jbuchbinder/5513891
-// This function is needed because windows notifications do not deal with UTF-8
-func utf8toASCII(s string) string {
-	t := make([]byte, utf8.RuneCountInString(s))
-	i := 0
-	for _, r := range s {
-		t[i] = byte(r)
-		i++
+// iconLocation returns the path of the cherwell logo presented in the notifications, or empty if it could not be extracted
+func iconLocation() string {
+	location, err := assets.Path(assets.CherwellLogo)
+	if err != nil {
+		logger.Warnf("The cherwell logo could not be extracted, the notifications will not show it. %v", err)
+		return ""
 	}
-	return string(t)
+	return location
 }
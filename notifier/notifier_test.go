@@ -0,0 +1,98 @@
+package notifier
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// failingNotifier stands in for a backend that cannot be reached
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(notification Notification) error {
+	return errors.New("backend unavailable")
+}
+
+func TestNew(t *testing.T) {
+	webhook := config.Webhook{URL: "https://example.com/hook"}
+	email := config.Email{Host: "smtp.example.com", Port: 25, From: "cwnotifier@example.com", To: []string{"team@example.com"}}
+
+	tests := []struct {
+		name     string
+		backends []string
+		expected []reflect.Type
+	}{
+		{"log", []string{LogBackend}, []reflect.Type{reflect.TypeOf(LogNotifier{})}},
+		{"webhook", []string{WebhookBackend}, []reflect.Type{reflect.TypeOf(&WebhookNotifier{})}},
+		{"email", []string{EmailBackend}, []reflect.Type{reflect.TypeOf(&EmailNotifier{})}},
+		{"several", []string{LogBackend, WebhookBackend, EmailBackend}, []reflect.Type{reflect.TypeOf(LogNotifier{}), reflect.TypeOf(&WebhookNotifier{}), reflect.TypeOf(&EmailNotifier{})}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := New(config.Notifier{Backends: test.backends, Webhook: webhook, Email: email})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var types []reflect.Type
+			if multi, isMulti := n.(multiNotifier); isMulti {
+				for _, backend := range multi {
+					types = append(types, reflect.TypeOf(backend))
+				}
+			} else {
+				types = append(types, reflect.TypeOf(n))
+			}
+
+			if !reflect.DeepEqual(types, test.expected) {
+				t.Errorf("expected the backends %v, got %v", test.expected, types)
+			}
+		})
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	_, err := New(config.Notifier{Backends: []string{LogBackend, "pager"}})
+	if err == nil || !strings.Contains(err.Error(), "pager") {
+		t.Errorf("expected an error about the unknown backend, got %v", err)
+	}
+}
+
+func TestMultiNotifierKeepsNotifyingWhenABackendFails(t *testing.T) {
+	first, last := &Recorder{}, &Recorder{}
+	n := multiNotifier{first, failingNotifier{}, last}
+	notification := Notification{Title: "Title", Message: "Message", Items: []string{"123"}, Severity: SeverityUrgent}
+
+	err := n.Notify(notification)
+	if err == nil || !strings.Contains(err.Error(), "backend unavailable") {
+		t.Errorf("expected the error of the failing backend, got %v", err)
+	}
+
+	for i, recorder := range []*Recorder{first, last} {
+		if notifications := recorder.Notifications(); !reflect.DeepEqual(notifications, []Notification{notification}) {
+			t.Errorf("expected backend %v to receive the notification, got %v", i, notifications)
+		}
+	}
+}
+
+func TestMultiNotifierSucceeds(t *testing.T) {
+	recorder := &Recorder{}
+	if err := (multiNotifier{LogNotifier{}, recorder}).Notify(Notification{Title: "Title"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(recorder.Notifications()) != 1 {
+		t.Errorf("expected the notification to be recorded, got %v", recorder.Notifications())
+	}
+}
+
+func TestNotificationBody(t *testing.T) {
+	if body := (Notification{Message: "Message"}).Body(); body != "Message" {
+		t.Errorf("expected only the message, got %q", body)
+	}
+	if body := (Notification{Message: "Message", Items: []string{"1", "2"}}).Body(); body != "Message\n1\n2" {
+		t.Errorf("expected the message followed by the items, got %q", body)
+	}
+}
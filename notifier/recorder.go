@@ -0,0 +1,37 @@
+package notifier
+
+import (
+	"sync"
+)
+
+// Recorder keeps every notification it receives in memory. It is used by the check command, which reports
+// the notifications instead of emitting them, and is meant to be used in tests.
+type Recorder struct {
+	mutex         sync.Mutex
+	notifications []Notification
+}
+
+// Notify records the notification
+func (r *Recorder) Notify(notification Notification) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+// Notifications returns a copy of the recorded notifications
+func (r *Recorder) Notifications() []Notification {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Notification(nil), r.notifications...)
+}
+
+// Reset discards the recorded notifications
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.notifications = nil
+}
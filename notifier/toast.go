@@ -0,0 +1,63 @@
+//go:build windows
+// +build windows
+
+package notifier
+
+import (
+	"unicode/utf8"
+
+	"gopkg.in/toast.v1"
+)
+
+// toastNotifier emits windows toast notifications
+type toastNotifier struct {
+	icon string
+}
+
+func newToastNotifier(icon string) Notifier {
+	return toastNotifier{icon: icon}
+}
+
+func (t toastNotifier) Notify(notification Notification) error {
+	toastNotification := toast.Notification{
+		AppID:    "CWNotifier",
+		Title:    utf8toASCII(notification.Title),
+		Message:  utf8toASCII(notification.Body()),
+		Icon:     t.icon,
+		Duration: "short",
+	}
+
+	if notification.Severity >= SeverityCritical {
+		toastNotification.Duration = "long"
+	}
+
+	// clicking the notification itself opens the first ticket
+	activation, actions := toastActions(notification.Actions)
+	if activation != "" {
+		toastNotification.ActivationType = "protocol"
+		toastNotification.ActivationArguments = activation
+	}
+
+	for _, action := range actions {
+		toastNotification.Actions = append(toastNotification.Actions, toast.Action{
+			Type:      "protocol",
+			Label:     utf8toASCII(action.Label),
+			Arguments: action.URL,
+		})
+	}
+
+	return toastNotification.Push()
+}
+
+// utf8toASCII converts a UTF-8 internal string representation to standard
+// ASCII bytes. Code from: https://gist.github.com/jbuchbinder/5513891
+// This function is needed because windows notifications do not deal with UTF-8
+func utf8toASCII(s string) string {
+	t := make([]byte, utf8.RuneCountInString(s))
+	i := 0
+	for _, r := range s {
+		t[i] = byte(r)
+		i++
+	}
+	return string(t)
+}
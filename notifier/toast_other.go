@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package notifier
+
+import (
+	"errors"
+)
+
+// toastNotifier is not available outside windows, it always returns an error
+type toastNotifier struct{}
+
+func newToastNotifier(icon string) Notifier {
+	return toastNotifier{}
+}
+
+func (t toastNotifier) Notify(notification Notification) error {
+	return errors.New("Toast notifications are only supported on windows")
+}
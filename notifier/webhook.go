@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// WebhookNotifier posts the notifications as JSON to an url, such as a chat incoming webhook or an alerting service
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// webhookPayload is the JSON body posted to the webhook. Text holds the whole notification as plain text,
+// which is the field used by the incoming webhooks of most chat services
+type webhookPayload struct {
+	Text     string          `json:"text"`
+	Title    string          `json:"title"`
+	Message  string          `json:"message"`
+	Items    []string        `json:"items"`
+	Severity string          `json:"severity"`
+	Actions  []webhookAction `json:"actions,omitempty"`
+}
+
+type webhookAction struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// NewWebhookNotifier creates a notifier that posts the notifications to the configured url
+func NewWebhookNotifier(webhookConfig config.Webhook) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     webhookConfig.URL,
+		headers: webhookConfig.Headers,
+		client:  &http.Client{Timeout: webhookConfig.GetTimeout()},
+	}
+}
+
+// Notify posts the notification to the webhook
+func (w *WebhookNotifier) Notify(notification Notification) error {
+	payload := webhookPayload{
+		Text:     notification.Title + "\n" + notification.Body(),
+		Title:    notification.Title,
+		Message:  notification.Message,
+		Items:    notification.Items,
+		Severity: notification.Severity.String(),
+	}
+	for _, action := range notification.Actions {
+		payload.Actions = append(payload.Actions, webhookAction{Label: action.Label, URL: action.URL})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("Error posting notification to the webhook. %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("The webhook responded with status %v", response.Status)
+	}
+	return nil
+}
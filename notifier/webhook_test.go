@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	type request struct {
+		method      string
+		contentType string
+		token       string
+		payload     webhookPayload
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("expected a json body, got %q: %v", body, err)
+		}
+		requests <- request{r.Method, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), payload}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookNotifier(config.Webhook{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	err := webhook.Notify(Notification{
+		Title:    "Aviso de chamado prioritário sem responsável",
+		Message:  "Existem chamados sem responsável",
+		Items:    []string{"100 [P1] Sem acesso", "200 [P2] Impressora"},
+		Severity: SeverityUrgent,
+		Actions:  []Action{{Label: "Abrir 100", URL: "https://cherwell/incident/100"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	received := <-requests
+	if received.method != http.MethodPost || received.contentType != "application/json" || received.token != "Bearer token" {
+		t.Errorf("expected a json POST with the configured headers, got %v %q %q", received.method, received.contentType, received.token)
+	}
+	expected := webhookPayload{
+		Text:     "Aviso de chamado prioritário sem responsável\nExistem chamados sem responsável\n100 [P1] Sem acesso\n200 [P2] Impressora",
+		Title:    "Aviso de chamado prioritário sem responsável",
+		Message:  "Existem chamados sem responsável",
+		Items:    []string{"100 [P1] Sem acesso", "200 [P2] Impressora"},
+		Severity: "urgent",
+		Actions:  []webhookAction{{Label: "Abrir 100", URL: "https://cherwell/incident/100"}},
+	}
+	if !reflect.DeepEqual(received.payload, expected) {
+		t.Errorf("expected the payload %+v, got %+v", expected, received.payload)
+	}
+}
+
+func TestWebhookNotifyErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(config.Webhook{URL: server.URL}).Notify(Notification{Title: "Erro!"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected the status of the response to be reported, got %v", err)
+	}
+}
+
+func TestWebhookNotifyUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	if err := NewWebhookNotifier(config.Webhook{URL: server.URL}).Notify(Notification{Title: "Erro!"}); err == nil {
+		t.Error("expected the closed server to be reported")
+	}
+}
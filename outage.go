@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+)
+
+// initialRetryDelay is the delay before the first retry, it doubles at each new attempt
+var initialRetryDelay = 5 * time.Second
+
+const (
+	// maxRetryDelay limits the delay between the attempts
+	maxRetryDelay time.Duration = time.Minute
+)
+
+// retry executes the operation until it succeeds or the retries are exhausted, waiting an exponentially
+// increasing delay between the attempts. The error of the last attempt is returned.
+// The wait is interrupted when the context is done, in which case the context's error is returned.
+func retry(ctx context.Context, retries int, initialDelay time.Duration, operation func() error) error {
+	delay := initialDelay
+	for attempt := 0; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		logger.Warnf("Attempt %v of %v failed, retrying in %v. %v", attempt+1, retries+1, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		delay = nextRetryDelay(delay)
+	}
+}
+
+// nextRetryDelay doubles the delay between the attempts, up to maxRetryDelay
+func nextRetryDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// connectionMonitor keeps track of the failures to check cherwell. It shows the status of the connection
+// in the system tray and notifies the user only when the outage lasts longer than the threshold.
+type connectionMonitor struct {
+	threshold      time.Duration
+	outageStart    time.Time
+	outageNotified bool
+}
+
+func newConnectionMonitor(threshold time.Duration) *connectionMonitor {
+	return &connectionMonitor{threshold: threshold}
+}
+
+// failed registers a failure to check cherwell
+func (m *connectionMonitor) failed(err error, now time.Time) {
+	if m.outageStart.IsZero() {
+		m.outageStart = now
+		logger.Errorf("Lost the connection with cherwell. %v", err)
+		setConnectionStatus(false)
+	} else {
+		logger.Warnf("Still disconnected from cherwell since %v. %v", m.outageStart.Format("15:04"), err)
+	}
+
+	if !m.outageNotified && now.Sub(m.outageStart) >= m.threshold {
+		notifier.NotifyOutage(m.outageStart)
+		m.outageNotified = true
+	}
+}
+
+// succeeded registers a successful check of cherwell, ending the outage if there was one
+func (m *connectionMonitor) succeeded(now time.Time) {
+	if !m.outageStart.IsZero() {
+		logger.Infof("Connection with cherwell was restored after %v.", now.Sub(m.outageStart).Round(time.Second))
+		if m.outageNotified {
+			notifier.NotifyConnectionRestored()
+		}
+	}
+
+	setConnectionStatus(true)
+	m.outageStart = time.Time{}
+	m.outageNotified = false
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+)
+
+// useRecorder records the notifications emitted during the test
+func useRecorder(t *testing.T) *notifier.Recorder {
+	recorder := &notifier.Recorder{}
+	notifier.SetNotifier(recorder)
+	t.Cleanup(func() { notifier.SetNotifier(nil) })
+	return recorder
+}
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("connection lost")
+
+	tests := []struct {
+		name     string
+		retries  int
+		failures int
+		attempts int
+		err      error
+	}{
+		{"success", 3, 0, 1, nil},
+		{"recovered", 3, 2, 3, nil},
+		{"last attempt", 3, 3, 4, nil},
+		{"exhausted", 3, 10, 4, failure},
+		{"no retries", 0, 10, 1, failure},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.Background(), test.retries, time.Millisecond, func() error {
+				attempts++
+				if attempts <= test.failures {
+					return failure
+				}
+				return nil
+			})
+
+			if err != test.err {
+				t.Errorf("expected the error %v, got %v", test.err, err)
+			}
+			if attempts != test.attempts {
+				t.Errorf("expected %v attempts, got %v", test.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryWaitsBetweenAttempts(t *testing.T) {
+	start := time.Now()
+	retry(context.Background(), 3, 10*time.Millisecond, func() error {
+		return errors.New("connection lost")
+	})
+
+	// the delays double at each attempt: 10ms, 20ms and 40ms
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("expected the attempts to wait at least 70ms in total, got %v", elapsed)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	err := retry(ctx, 3, time.Hour, func() error {
+		attempts++
+		cancel()
+		return errors.New("connection lost")
+	})
+
+	if err != context.Canceled {
+		t.Errorf("expected the context's error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected the wait to be interrupted after the first attempt, got %v attempts", attempts)
+	}
+}
+
+func TestNextRetryDelay(t *testing.T) {
+	tests := []struct {
+		delay    time.Duration
+		expected time.Duration
+	}{
+		{5 * time.Second, 10 * time.Second},
+		{20 * time.Second, 40 * time.Second},
+		{40 * time.Second, maxRetryDelay},
+		{maxRetryDelay, maxRetryDelay},
+	}
+
+	for _, test := range tests {
+		if delay := nextRetryDelay(test.delay); delay != test.expected {
+			t.Errorf("expected the delay after %v to be %v, got %v", test.delay, test.expected, delay)
+		}
+	}
+}
+
+func TestConnectionMonitor(t *testing.T) {
+	recorder := useRecorder(t)
+	monitor := newConnectionMonitor(5 * time.Minute)
+	start := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+	failure := errors.New("connection lost")
+
+	steps := []struct {
+		elapsed time.Duration
+		failed  bool
+		titles  []string
+	}{
+		{0, true, nil},
+		{4 * time.Minute, true, nil},
+		{5 * time.Minute, true, []string{"Sem conexão com o cherwell"}},
+		{6 * time.Minute, true, nil},
+		{7 * time.Minute, false, []string{"Conexão com o cherwell restabelecida"}},
+		{8 * time.Minute, false, nil},
+		// a short outage is recovered without notifying the user
+		{9 * time.Minute, true, nil},
+		{10 * time.Minute, false, nil},
+		// a new outage is counted from its own start
+		{11 * time.Minute, true, nil},
+		{15 * time.Minute, true, nil},
+		{16 * time.Minute, true, []string{"Sem conexão com o cherwell"}},
+	}
+
+	for i, step := range steps {
+		recorder.Reset()
+		now := start.Add(step.elapsed)
+		if step.failed {
+			monitor.failed(failure, now)
+		} else {
+			monitor.succeeded(now)
+		}
+
+		var titles []string
+		for _, n := range recorder.Notifications() {
+			titles = append(titles, n.Title)
+		}
+		if len(titles) != len(step.titles) || (len(titles) > 0 && titles[0] != step.titles[0]) {
+			t.Errorf("step %v (%v): expected the notifications %v, got %v", i, step.elapsed, step.titles, titles)
+		}
+	}
+}
+
+func TestRecoverFromError(t *testing.T) {
+	recorder := useRecorder(t)
+
+	func() {
+		defer recoverFromError()
+		panic("unexpected")
+	}()
+
+	select {
+	case <-crashed:
+	default:
+		t.Error("expected the panic to make run shut the program down")
+	}
+	if notifications := recorder.Notifications(); len(notifications) != 1 || notifications[0].Title != "Erro!" {
+		t.Errorf("expected the error to be notified, got %+v", notifications)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// readPassword reads a line from the file without echoing it, when the file is a terminal
+func readPassword(file *os.File) (string, error) {
+	fd := file.Fd()
+
+	var state syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&state))); errno != 0 {
+		// not a terminal, such as when the password is piped
+		return readLine(file)
+	}
+
+	noEcho := state
+	noEcho.Lflag &^= syscall.ECHO
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&noEcho))); errno != 0 {
+		return "", errno
+	}
+	defer syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&state)))
+
+	return readLine(file)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package main
+
+import (
+	"os"
+)
+
+// readPassword reads a line from the file. Outside linux and windows the typed password is echoed.
+func readPassword(file *os.File) (string, error) {
+	return readLine(file)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableEchoInput is the console mode that echoes the typed characters
+const enableEchoInput uint32 = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// readPassword reads a line from the file without echoing it, when the file is a console
+func readPassword(file *os.File) (string, error) {
+	handle := syscall.Handle(file.Fd())
+
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		// not a console, such as when the password is piped
+		return readLine(file)
+	}
+
+	if result, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); result == 0 {
+		return "", err
+	}
+	defer procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+
+	return readLine(file)
+}
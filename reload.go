@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+)
+
+// configurationPollInterval is how often the configuration file is checked for changes
+const configurationPollInterval time.Duration = 5 * time.Second
+
+// watchConfiguration checks the configuration file for changes and sends the new configuration to the reloads channel,
+// until the context is done. When the new configuration is invalid it is not sent, the user is warned and the current configuration is kept.
+func watchConfiguration(ctx context.Context, yamlLocation string, interval time.Duration, reloads chan<- config.Configuration) {
+	lastModification := modificationTime(yamlLocation)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		modification := modificationTime(yamlLocation)
+		if modification.Equal(lastModification) {
+			continue
+		}
+		lastModification = modification
+
+		logger.Infof("The configuration file \"%v\" was changed, reloading it.", yamlLocation)
+		configuration, err := readConfiguration(yamlLocation)
+		if err != nil {
+			logger.Errorf("The new configuration is invalid, keeping the previous one. %v", err)
+			notifier.NotifyInvalidConfiguration()
+			continue
+		}
+
+		select {
+		case reloads <- configuration:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// modificationTime returns when the file was last modified, or the zero time if it could not be read
+func modificationTime(fileLocation string) time.Time {
+	fileInfo, err := os.Stat(fileLocation)
+	if err != nil {
+		return time.Time{}
+	}
+	return fileInfo.ModTime()
+}
+
+// applyConfiguration replaces the configuration of the running program and returns the new configuration.
+// The connection with the data source is only closed, to be opened again by the next check, when its settings changed.
+// It must be called while no check is running.
+func applyConfiguration(previous config.Configuration, configuration config.Configuration, monitor *connectionMonitor) config.Configuration {
+	if err := applySettings(configuration); err != nil {
+		logger.Errorf("Error applying the log and assets settings. %v", err)
+	}
+
+	if !reflect.DeepEqual(previous.Notifier, configuration.Notifier) || previous.Assets != configuration.Assets {
+		backend, err := notifier.New(configuration.Notifier)
+		if err != nil {
+			logger.Errorf("Error creating the notification backend, keeping the previous one. %v", err)
+			configuration.Notifier = previous.Notifier
+		} else {
+			notifier.SetNotifier(backend)
+		}
+	}
+
+	notificationTracker.SetEscalationInterval(configuration.Job.GetEscalationInterval())
+	monitor.threshold = configuration.Job.GetOutageThreshold()
+
+	if previous.GetDataSource() != configuration.GetDataSource() ||
+		!reflect.DeepEqual(previous.Database, configuration.Database) ||
+		!reflect.DeepEqual(previous.Schema, configuration.Schema) ||
+		!reflect.DeepEqual(previous.Cherwell, configuration.Cherwell) ||
+		previous.Fixture != configuration.Fixture ||
+		(configuration.GetDataSource() == config.RESTDataSource && previous.Job.Timezone != configuration.Job.Timezone) {
+		logger.Infof("The data source settings changed, reconnecting.")
+		closeDataSource()
+	}
+
+	if !configuration.IsNotificationsEnabled() {
+		logger.Infof("There are no notifications enabled in the new configuration.")
+	}
+
+	logger.Infof("The new configuration was applied.")
+	return configuration
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+	"github.com/pedroppinheiro/cwnotifier/tracker"
+)
+
+// writeConfiguration writes a configuration file that reads the given fixture, with the given job.sleepMinutes.
+// The modification time is set explicitly, since the file may be written more than once within the resolution of the file system.
+func writeConfiguration(t *testing.T, yamlLocation string, fixture string, sleepMinutes int, modification time.Time) {
+	content := fmt.Sprintf("dataSource: \"fixture\"\nfixture: %q\nuser:\n  team: \"Support\"\njob:\n  start: \"00:00\"\n  end: \"23:59\"\n  sleepMinutes: %v\n", fixture, sleepMinutes)
+	if err := ioutil.WriteFile(yamlLocation, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(yamlLocation, modification, modification); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchConfiguration(t *testing.T) {
+	recorder := useRecorder(t)
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixture.yaml")
+	if err := ioutil.WriteFile(fixture, []byte("incidentsWithoutOwner: []\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	yamlLocation := filepath.Join(dir, "config.yaml")
+	modification := time.Now().Add(-time.Hour)
+	writeConfiguration(t, yamlLocation, fixture, 5, modification)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan config.Configuration)
+	go watchConfiguration(ctx, yamlLocation, 10*time.Millisecond, reloads)
+
+	// the file is not reloaded while it is not changed
+	select {
+	case configuration := <-reloads:
+		t.Fatalf("expected the unchanged file not to be reloaded, got %+v", configuration.Job)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeConfiguration(t, yamlLocation, fixture, 10, modification.Add(time.Minute))
+	select {
+	case configuration := <-reloads:
+		if configuration.Job.SleepMinutes != 10 {
+			t.Errorf("expected the new configuration to be sent, got %+v", configuration.Job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the changed file to be reloaded")
+	}
+
+	writeConfiguration(t, yamlLocation, filepath.Join(dir, "missing.yaml"), 15, modification.Add(2*time.Minute))
+	select {
+	case configuration := <-reloads:
+		t.Fatalf("expected the invalid configuration not to be sent, got %+v", configuration.Job)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if notifications := recorder.Notifications(); len(notifications) != 1 || notifications[0].Title != "Configuração inválida" {
+		t.Errorf("expected the invalid configuration to be notified, got %+v", notifications)
+	}
+}
+
+func TestApplyConfigurationNotifier(t *testing.T) {
+	recorder := useMemory(t, &datasource.Memory{}, time.Hour)
+	monitor := newConnectionMonitor(time.Minute)
+	previous := config.Configuration{DataSource: config.FixtureDataSource, Fixture: "fixture.yaml"}
+
+	previous = applyConfiguration(previous, previous, monitor)
+	notifier.NotifyProgramStart()
+	if len(recorder.Notifications()) != 1 {
+		t.Fatal("expected the notifier to be kept when its settings did not change")
+	}
+
+	changed := previous
+	changed.Notifier = config.Notifier{Backends: []string{notifier.LogBackend}}
+	changed = applyConfiguration(previous, changed, monitor)
+	notifier.NotifyProgramStart()
+	if len(recorder.Notifications()) != 1 {
+		t.Error("expected the notifier to be replaced when its settings changed")
+	}
+
+	invalid := changed
+	invalid.Notifier = config.Notifier{Backends: []string{"pager"}}
+	if applied := applyConfiguration(changed, invalid, monitor); !reflect.DeepEqual(applied.Notifier, changed.Notifier) {
+		t.Errorf("expected the previous notifier to be kept when the new one cannot be created, got %v", applied.Notifier)
+	}
+}
+
+func TestApplyConfigurationReconnects(t *testing.T) {
+	memory := &datasource.Memory{}
+	useMemory(t, memory, time.Hour)
+	sql := config.Configuration{DataSource: config.SQLDataSource, Database: config.Database{Server: "cherwell", DatabaseName: "cherwell"}}
+	rest := config.Configuration{DataSource: config.RESTDataSource, Cherwell: config.Cherwell{URL: "https://cherwell"}}
+	fixture := config.Configuration{DataSource: config.FixtureDataSource, Fixture: "fixture.yaml"}
+
+	withChange := func(c config.Configuration, change func(*config.Configuration)) config.Configuration {
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name          string
+		previous      config.Configuration
+		configuration config.Configuration
+		reconnects    bool
+	}{
+		{"unchanged", sql, sql, false},
+		{"job", sql, withChange(sql, func(c *config.Configuration) { c.Job.SleepMinutes = 10 }), false},
+		{"notifier", sql, withChange(sql, func(c *config.Configuration) { c.Notifier.Backends = []string{"log"} }), false},
+		{"data source", sql, fixture, true},
+		{"database", sql, withChange(sql, func(c *config.Configuration) { c.Database.Server = "other" }), true},
+		{"schema", sql, withChange(sql, func(c *config.Configuration) { c.Schema.Tables = map[string]string{"incident": "dbo.Incidente2"} }), true},
+		{"cherwell", rest, withChange(rest, func(c *config.Configuration) { c.Cherwell.URL = "https://other" }), true},
+		{"rest time zone", rest, withChange(rest, func(c *config.Configuration) { c.Job.Timezone = "America/Sao_Paulo" }), true},
+		{"sql time zone", sql, withChange(sql, func(c *config.Configuration) { c.Job.Timezone = "America/Sao_Paulo" }), false},
+		{"fixture", fixture, withChange(fixture, func(c *config.Configuration) { c.Fixture = "other.yaml" }), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataSource = memory
+			applyConfiguration(test.previous, test.configuration, newConnectionMonitor(time.Minute))
+
+			if reconnected := dataSource == nil; reconnected != test.reconnects {
+				t.Errorf("expected the data source to be reconnected: %v, got %v", test.reconnects, reconnected)
+			}
+		})
+	}
+}
+
+func TestApplyConfigurationJob(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	monitor := newConnectionMonitor(time.Minute)
+	configuration := config.Configuration{Job: config.Job{EscalationMinutes: 30, OutageMinutes: 10}}
+
+	applyConfiguration(config.Configuration{}, configuration, monitor)
+
+	if monitor.threshold != 10*time.Minute {
+		t.Errorf("expected the outage threshold to be updated, got %v", monitor.threshold)
+	}
+	now := time.Now()
+	notificationTracker.Due("incidentsWithoutOwner", tracker.Items([]string{"1"}), now)
+	if due := notificationTracker.Due("incidentsWithoutOwner", tracker.Items([]string{"1"}), now.Add(30*time.Minute)); len(due) != 1 {
+		t.Errorf("expected the escalation interval to be updated to 30 minutes, got %v due", due)
+	}
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+var logger = logging.New("scheduler")
+
+// Task is a function that is executed according to its schedule.
+// The context given to Run is cancelled when the scheduler is stopped.
+type Task struct {
+	Name     string
+	Schedule cron.Schedule
+	Run      func(ctx context.Context)
+}
+
+// Scheduler runs each task independently, on its own schedule, until it is stopped
+type Scheduler struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start runs every task on its schedule until the context is done or the scheduler is stopped.
+// When runNow is true the tasks are also executed immediately, instead of waiting for their first scheduled time.
+func Start(ctx context.Context, tasks []Task, runNow bool) *Scheduler {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scheduler{cancel: cancel}
+
+	for _, task := range tasks {
+		s.wg.Add(1)
+		go s.run(ctx, task, runNow)
+	}
+
+	return s
+}
+
+func (s *Scheduler) run(ctx context.Context, task Task, runNow bool) {
+	defer s.wg.Done()
+
+	if runNow {
+		task.Run(ctx)
+	}
+
+	for {
+		next := task.Schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Warnf("%v has no next scheduled time, it will not be checked again.", task.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			task.Run(ctx)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Stop cancels the tasks and waits for the ones that are running to finish
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// every runs a task at a fixed interval, shorter than the second supported by the cron intervals
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// never has no next time
+type never struct{}
+
+func (never) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// counter counts the runs of a task
+type counter struct {
+	runs int32
+}
+
+func (c *counter) run(ctx context.Context) {
+	atomic.AddInt32(&c.runs, 1)
+}
+
+func (c *counter) count() int {
+	return int(atomic.LoadInt32(&c.runs))
+}
+
+// waitFor waits until the condition is true, failing the test when it takes too long
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", description)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStartRunsNow(t *testing.T) {
+	c := &counter{}
+	s := Start(context.Background(), []Task{{Name: "now", Schedule: every(time.Hour), Run: c.run}}, true)
+	defer s.Stop()
+
+	waitFor(t, "the task to run immediately", func() bool { return c.count() == 1 })
+}
+
+func TestStartWaitsForTheSchedule(t *testing.T) {
+	c := &counter{}
+	s := Start(context.Background(), []Task{{Name: "later", Schedule: every(time.Hour), Run: c.run}}, false)
+
+	time.Sleep(20 * time.Millisecond)
+	s.Stop()
+	if c.count() != 0 {
+		t.Errorf("expected the task not to run before its scheduled time, got %v runs", c.count())
+	}
+}
+
+func TestStartRunsOnSchedule(t *testing.T) {
+	c := &counter{}
+	s := Start(context.Background(), []Task{{Name: "often", Schedule: every(5 * time.Millisecond), Run: c.run}}, false)
+	defer s.Stop()
+
+	waitFor(t, "the task to run repeatedly", func() bool { return c.count() >= 3 })
+}
+
+func TestStartWithoutNextTime(t *testing.T) {
+	c := &counter{}
+	s := Start(context.Background(), []Task{{Name: "once", Schedule: never{}, Run: c.run}}, true)
+
+	// Stop returns right away, since the task is no longer scheduled
+	s.Stop()
+	if c.count() != 1 {
+		t.Errorf("expected the task to run only once, got %v runs", c.count())
+	}
+}
+
+func TestTasksRunIndependently(t *testing.T) {
+	blocked := make(chan struct{})
+	slow := func(ctx context.Context) {
+		select {
+		case <-blocked:
+		case <-ctx.Done():
+		}
+	}
+	c := &counter{}
+
+	s := Start(context.Background(), []Task{
+		{Name: "slow", Schedule: every(time.Hour), Run: slow},
+		{Name: "fast", Schedule: every(5 * time.Millisecond), Run: c.run},
+	}, true)
+	defer s.Stop()
+	defer close(blocked)
+
+	waitFor(t, "the fast task to run while the slow one is running", func() bool { return c.count() >= 3 })
+}
+
+func TestStopCancelsAndWaitsForTheTasks(t *testing.T) {
+	started := make(chan struct{})
+	var finished int32
+	task := func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	}
+
+	s := Start(context.Background(), []Task{{Name: "running", Schedule: every(time.Hour), Run: task}}, true)
+	<-started
+	s.Stop()
+
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("expected Stop to wait for the running task to finish")
+	}
+}
+
+func TestStopWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &counter{}
+	s := Start(ctx, []Task{{Name: "often", Schedule: every(5 * time.Millisecond), Run: c.run}}, false)
+
+	cancel()
+	s.Stop()
+	runs := c.count()
+	time.Sleep(20 * time.Millisecond)
+	if c.count() != runs {
+		t.Errorf("expected the task not to run after the context is done, got %v more runs", c.count()-runs)
+	}
+}
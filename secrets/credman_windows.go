@@ -0,0 +1,97 @@
+//go:build windows
+// +build windows
+
+package secrets
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Values defined by the Credential Manager API. See https://docs.microsoft.com/en-us/windows/win32/api/wincred/
+const (
+	credTypeGeneric         uint32        = 1
+	credPersistLocalMachine uint32        = 2
+	errorNotFound           syscall.Errno = 1168
+	credentialTargetPrefix  string        = "cwnotifier:"
+	maxCredentialBlobSize   int           = 5 * 512
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialManager keeps the secrets in the windows Credential Manager, where they are protected by the user's logon
+type credentialManager struct{}
+
+func systemStore() (store, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, err
+	}
+	return credentialManager{}, nil
+}
+
+func (credentialManager) get(account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(credentialTargetPrefix + account)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	result, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), uintptr(credTypeGeneric), 0, uintptr(unsafe.Pointer(&cred)))
+	if result == 0 {
+		if err == errorNotFound {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := (*[maxCredentialBlobSize]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	return string(blob), nil
+}
+
+func (credentialManager) set(account string, secret string) error {
+	target, err := syscall.UTF16PtrFromString(credentialTargetPrefix + account)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	if result, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); result == 0 {
+		return err
+	}
+	return nil
+}
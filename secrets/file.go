@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	secretsFileName string = "secrets.json"
+	keyFileName     string = "secrets.key"
+	keySize         int    = 32
+)
+
+// fileStore keeps the secrets encrypted with AES-GCM in the user's configuration directory.
+// The key is kept in a separate file that only the user can read, so the secrets are protected from whoever
+// gets a copy of the secrets file, such as in a backup, but not from whoever can read the user's files.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore() fileStore {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return fileStore{dir: filepath.Join(dir, "cwnotifier")}
+}
+
+func (f fileStore) get(account string) (string, error) {
+	secrets, err := f.read()
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, ok := secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	key, err := f.key(false)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return "", fmt.Errorf("The password of \"%v\" in %v is corrupted", account, secretsFileName)
+	}
+	nonce, ciphertext := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, []byte(account))
+	if err != nil {
+		return "", fmt.Errorf("Error decrypting the password of \"%v\". %w", account, err)
+	}
+	return string(secret), nil
+}
+
+func (f fileStore) set(account string, secret string) error {
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	key, err := f.key(true)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	secrets[account] = gcm.Seal(nonce, nonce, []byte(secret), []byte(account))
+
+	content, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(f.dir, secretsFileName), content, 0600)
+}
+
+// read returns the encrypted secrets by account. The secrets are encoded as base64 by encoding/json.
+func (f fileStore) read() (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+
+	content, err := ioutil.ReadFile(filepath.Join(f.dir, secretsFileName))
+	if os.IsNotExist(err) {
+		return secrets, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &secrets); err != nil {
+		return nil, fmt.Errorf("Error reading %v. %w", secretsFileName, err)
+	}
+	return secrets, nil
+}
+
+// key returns the encryption key, which is created when create is true and there is no key yet
+func (f fileStore) key(create bool) ([]byte, error) {
+	keyLocation := filepath.Join(f.dir, keyFileName)
+
+	key, err := ioutil.ReadFile(keyLocation)
+	if err == nil {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("The key in %v is invalid", keyLocation)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !create {
+		return nil, errors.New("There is no key to decrypt the passwords, they must be stored again")
+	}
+
+	key = make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(keyLocation, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+// ErrNotFound is returned when there is no secret stored for the account
+var ErrNotFound = errors.New("There is no password stored for the account")
+
+var logger = logging.New("secrets")
+
+// store keeps the secrets of each account
+type store interface {
+	get(account string) (string, error)
+	set(account string, secret string) error
+}
+
+// Get returns the secret of the account from the operating system's secret store, which is the Credential Manager on windows
+// and the freedesktop Secret Service on linux. When the secret store is not available, or has no secret for the account
+// because it was saved while the secret store was not available, the encrypted file is used instead.
+func Get(account string) (string, error) {
+	return get(systemStore, newFileStore(), account)
+}
+
+func get(systemStore func() (store, error), fallback store, account string) (string, error) {
+	s, err := systemStore()
+	if err != nil {
+		logger.Warnf("The secret store is not available, using the encrypted file. %v", err)
+		return fallback.get(account)
+	}
+
+	secret, err := s.get(account)
+	if errors.Is(err, ErrNotFound) {
+		logger.Infof("The secret store has no password for %v, using the encrypted file.", account)
+		return fallback.get(account)
+	}
+	return secret, err
+}
+
+// Set stores the secret of the account in the operating system's secret store or, when it is not available, in the encrypted file
+func Set(account string, secret string) error {
+	s, err := systemStore()
+	if err != nil {
+		logger.Warnf("The secret store is not available, using the encrypted file. %v", err)
+		return newFileStore().set(account, secret)
+	}
+	return s.set(account, secret)
+}
+
+// DatabasePassword returns the database password from the source given in the configuration.
+// The password is masked in the log.
+func DatabasePassword(databaseConfig config.Database) (string, error) {
+	password, err := databasePassword(databaseConfig)
+	if err != nil {
+		return "", err
+	}
+	logging.AddSecret(password)
+	return password, nil
+}
+
+func databasePassword(databaseConfig config.Database) (string, error) {
+	switch {
+	case databaseConfig.PasswordEnv != "":
+		password, ok := os.LookupEnv(databaseConfig.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("The environment variable \"%v\" of database.passwordEnv is not set", databaseConfig.PasswordEnv)
+		}
+		return password, nil
+	case databaseConfig.PasswordFile != "":
+		content, err := ioutil.ReadFile(databaseConfig.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("Error reading database.passwordFile. %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	case databaseConfig.PasswordStore:
+		password, err := Get(databaseConfig.PasswordAccount())
+		if err != nil {
+			return "", fmt.Errorf("Error reading the database password from the secret store. %w", err)
+		}
+		return password, nil
+	}
+	return databaseConfig.Password, nil
+}
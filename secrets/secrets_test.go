@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+// memoryStore stands in for the operating system's secret store
+type memoryStore map[string]string
+
+func (m memoryStore) get(account string) (string, error) {
+	secret, ok := m[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m memoryStore) set(account string, secret string) error {
+	m[account] = secret
+	return nil
+}
+
+func TestGet(t *testing.T) {
+	file := fileStore{dir: t.TempDir()}
+	if err := file.set("database:file", "saved while the store was down"); err != nil {
+		t.Fatal(err)
+	}
+
+	system := memoryStore{"database:system": "saved in the store", "database:both": "from the store"}
+	if err := file.set("database:both", "from the file"); err != nil {
+		t.Fatal(err)
+	}
+
+	available := func() (store, error) { return system, nil }
+	unavailable := func() (store, error) { return nil, errors.New("no session bus") }
+
+	tests := []struct {
+		name        string
+		systemStore func() (store, error)
+		account     string
+		expected    string
+		err         error
+	}{
+		{"system store", available, "database:system", "saved in the store", nil},
+		{"system store takes precedence", available, "database:both", "from the store", nil},
+		{"file when the system store has no password", available, "database:file", "saved while the store was down", nil},
+		{"file when the system store is unavailable", unavailable, "database:file", "saved while the store was down", nil},
+		{"not found in either", available, "database:missing", "", ErrNotFound},
+		{"not found in the file", unavailable, "database:system", "", ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret, err := get(test.systemStore, file, test.account)
+			if secret != test.expected || !errors.Is(err, test.err) {
+				t.Errorf("expected %q and %v, got %q and %v", test.expected, test.err, secret, err)
+			}
+		})
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	file := fileStore{dir: t.TempDir()}
+	if err := file.set("database:user@server/db", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.set("database:user@server/db", "changed"); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := fileStore{dir: file.dir}.get("database:user@server/db")
+	if err != nil || secret != "changed" {
+		t.Errorf("expected the changed secret, got %q and %v", secret, err)
+	}
+}
@@ -0,0 +1,198 @@
+//go:build !windows
+// +build !windows
+
+package secrets
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Names defined by the Secret Service API specification.
+// See https://specifications.freedesktop.org/secret-service-spec/latest/
+const (
+	secretServiceDestination       string          = "org.freedesktop.secrets"
+	secretServicePath              dbus.ObjectPath = "/org/freedesktop/secrets"
+	secretServiceInterface         string          = "org.freedesktop.Secret.Service"
+	secretCollectionInterface      string          = "org.freedesktop.Secret.Collection"
+	secretItemInterface            string          = "org.freedesktop.Secret.Item"
+	secretSessionInterface         string          = "org.freedesktop.Secret.Session"
+	secretPromptInterface          string          = "org.freedesktop.Secret.Prompt"
+	secretDefaultCollection        dbus.ObjectPath = "/org/freedesktop/secrets/aliases/default"
+	secretNoPrompt                 dbus.ObjectPath = "/"
+	secretPlainAlgorithm           string          = "plain"
+	secretApplicationAttributeName string          = "application"
+	secretAccountAttributeName     string          = "account"
+	secretApplicationName          string          = "cwnotifier"
+)
+
+// promptTimeout limits how long the user has to answer the secret store's prompt, such as to unlock the keyring
+const promptTimeout time.Duration = 2 * time.Minute
+
+// secretValue is the Secret structure of the Secret Service API
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretService keeps the secrets in the freedesktop Secret Service, provided by GNOME Keyring and KWallet.
+// The secrets are transferred unencrypted through the session bus, which only the user can access.
+type secretService struct {
+	conn *dbus.Conn
+}
+
+func systemStore() (store, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	// the ping starts the secret service when it is activated on demand
+	if err := conn.Object(secretServiceDestination, secretServicePath).Call("org.freedesktop.DBus.Peer.Ping", 0).Err; err != nil {
+		return nil, err
+	}
+	return secretService{conn: conn}, nil
+}
+
+func (s secretService) get(account string) (string, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.service().Call(secretServiceInterface+".SearchItems", 0, attributes(account)).Store(&unlocked, &locked)
+	if err != nil {
+		return "", err
+	}
+
+	var item dbus.ObjectPath
+	switch {
+	case len(unlocked) > 0:
+		item = unlocked[0]
+	case len(locked) > 0:
+		item = locked[0]
+		if err := s.unlock(item); err != nil {
+			return "", err
+		}
+	default:
+		return "", ErrNotFound
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+
+	var secret secretValue
+	if err := s.conn.Object(secretServiceDestination, item).Call(secretItemInterface+".GetSecret", 0, session).Store(&secret); err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
+
+func (s secretService) set(account string, secret string) error {
+	if err := s.unlock(secretDefaultCollection); err != nil {
+		return err
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+
+	properties := map[string]dbus.Variant{
+		secretItemInterface + ".Label":      dbus.MakeVariant("CWNotifier - " + account),
+		secretItemInterface + ".Attributes": dbus.MakeVariant(attributes(account)),
+	}
+	value := secretValue{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       []byte(secret),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err = s.conn.Object(secretServiceDestination, secretDefaultCollection).Call(secretCollectionInterface+".CreateItem", 0, properties, value, true).Store(&item, &prompt)
+	if err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+func (s secretService) service() dbus.BusObject {
+	return s.conn.Object(secretServiceDestination, secretServicePath)
+}
+
+func (s secretService) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := s.service().Call(secretServiceInterface+".OpenSession", 0, secretPlainAlgorithm, dbus.MakeVariant("")).Store(&output, &session)
+	return session, err
+}
+
+func (s secretService) closeSession(session dbus.ObjectPath) {
+	if err := s.conn.Object(secretServiceDestination, session).Call(secretSessionInterface+".Close", 0).Err; err != nil {
+		logger.Warnf("Error closing the secret service session. %v", err)
+	}
+}
+
+// unlock unlocks the item or collection, asking the user when the secret store requires it
+func (s secretService) unlock(object dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.service().Call(secretServiceInterface+".Unlock", 0, []dbus.ObjectPath{object}).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+// prompt shows the prompt of the secret store, if there is one, and waits for the user to complete it
+func (s secretService) prompt(prompt dbus.ObjectPath) error {
+	if prompt == secretNoPrompt {
+		return nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 10)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceDestination, prompt).Call(secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(promptTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != prompt || signal.Name != secretPromptInterface+".Completed" || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return errors.New("The secret store prompt was dismissed")
+			}
+			return nil
+		case <-timeout.C:
+			return errors.New("The secret store prompt was not answered")
+		}
+	}
+}
+
+func attributes(account string) map[string]string {
+	return map[string]string{
+		secretApplicationAttributeName: secretApplicationName,
+		secretAccountAttributeName:     account,
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+	"github.com/pedroppinheiro/cwnotifier/datasource"
+	"github.com/pedroppinheiro/cwnotifier/scheduler"
+)
+
+// shutdownEvents records the steps of the shutdown in the order they happen
+type shutdownEvents struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (e *shutdownEvents) record(event string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *shutdownEvents) list() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string{}, e.events...)
+}
+
+// closingDataSource records when the data source is closed
+type closingDataSource struct {
+	*datasource.Memory
+	events *shutdownEvents
+}
+
+func (d closingDataSource) Close() {
+	d.events.record("data source closed")
+}
+
+// startCheck schedules a check that runs right away, as run does when the program starts
+func startCheck(t *testing.T, ctx context.Context, c scheduledCheck) *scheduler.Scheduler {
+	schedule, err := config.ParseSchedule("@every 1h", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration := testConfiguration()
+	monitor := newConnectionMonitor(time.Hour)
+
+	return scheduler.Start(ctx, []scheduler.Task{{Name: c.name, Schedule: schedule, Run: func(ctx context.Context) {
+		check(ctx, configuration, monitor, c)
+	}}}, true)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	events := &shutdownEvents{}
+	dataSource = closingDataSource{&datasource.Memory{}, events}
+
+	started := make(chan struct{})
+	// the query in flight returns once it is cancelled. It reports no error, so that the data source is only closed by the shutdown
+	inFlight := scheduledCheck{name: "inFlight", run: func(ctx context.Context, configuration config.Configuration) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		events.record("check finished")
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checks := startCheck(t, ctx, inFlight)
+	<-started
+
+	events.record("context cancelled")
+	cancel()
+	shutdown(checks)
+	events.record("shut down")
+
+	expected := []string{"context cancelled", "check finished", "data source closed", "shut down"}
+	if recorded := events.list(); !reflect.DeepEqual(recorded, expected) {
+		t.Errorf("expected the shutdown steps %v, got %v", expected, recorded)
+	}
+	if dataSource != nil {
+		t.Error("expected the data source to be released")
+	}
+}
+
+func TestShutdownStopsTheScheduler(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	events := &shutdownEvents{}
+	dataSource = closingDataSource{&datasource.Memory{}, events}
+
+	runs := make(chan struct{}, 10)
+	counted := scheduledCheck{name: "counted", run: func(ctx context.Context, configuration config.Configuration) error {
+		runs <- struct{}{}
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checks := startCheck(t, ctx, counted)
+	<-runs
+	cancel()
+	shutdown(checks)
+
+	// the scheduler no longer starts checks, even if the data source is connected again
+	dataSource = closingDataSource{&datasource.Memory{}, events}
+	select {
+	case <-runs:
+		t.Error("expected no check to run after the shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestShutdownAbandonsAStuckCheck(t *testing.T) {
+	useMemory(t, &datasource.Memory{}, time.Hour)
+	previousTimeout := shutdownTimeout
+	shutdownTimeout = 20 * time.Millisecond
+	defer func() { shutdownTimeout = previousTimeout }()
+	events := &shutdownEvents{}
+	dataSource = closingDataSource{&datasource.Memory{}, events}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	// the query ignores the cancellation, as a driver that does not support it
+	stuck := scheduledCheck{name: "stuck", run: func(ctx context.Context, configuration config.Configuration) error {
+		defer close(finished)
+		close(started)
+		<-release
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checks := startCheck(t, ctx, stuck)
+	<-started
+
+	cancel()
+	start := time.Now()
+	shutdown(checks)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the stuck check to be abandoned after %v, it took %v", shutdownTimeout, elapsed)
+	}
+	if recorded := events.list(); !reflect.DeepEqual(recorded, []string{"data source closed"}) {
+		t.Errorf("expected the data source to be closed without waiting for the stuck check, got %v", recorded)
+	}
+
+	close(release)
+	<-finished
+	checks.Stop()
+}
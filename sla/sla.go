@@ -0,0 +1,87 @@
+package sla
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+// Level is how close a ticket is to breaching its SLA
+type Level int
+
+const (
+	// LevelNone is used when the ticket has no SLA or is below the normal threshold
+	LevelNone Level = iota
+	// LevelNormal is used when the elapsed SLA reached the normal threshold
+	LevelNormal
+	// LevelUrgent is used when the elapsed SLA reached the urgent threshold
+	LevelUrgent
+	// LevelCritical is used when the SLA was breached
+	LevelCritical
+)
+
+// Status is the SLA situation of a ticket at a given time
+type Status struct {
+	Level Level
+	// Elapsed is the percentage of the SLA time that has passed
+	Elapsed float64
+	// Remaining is the time until the deadline, negative when it was breached
+	Remaining time.Duration
+}
+
+// Evaluate computes the SLA status of a ticket created at the given time and that must be handled until the deadline.
+// Tickets without a deadline always have LevelNone.
+func Evaluate(createdAt time.Time, deadline time.Time, now time.Time, threshold config.SLAThreshold) Status {
+	if deadline.IsZero() {
+		return Status{Level: LevelNone}
+	}
+
+	status := Status{Remaining: deadline.Sub(now)}
+
+	total := deadline.Sub(createdAt)
+	if createdAt.IsZero() || total <= 0 {
+		status.Elapsed = 0
+	} else {
+		status.Elapsed = float64(now.Sub(createdAt)) / float64(total) * 100
+	}
+
+	switch {
+	case status.Remaining <= 0:
+		status.Level = LevelCritical
+	case status.Elapsed >= float64(threshold.Urgent):
+		status.Level = LevelUrgent
+	case status.Elapsed >= float64(threshold.Normal):
+		status.Level = LevelNormal
+	default:
+		status.Level = LevelNone
+	}
+
+	return status
+}
+
+// String describes the status in the way it is shown in the notifications
+func (s Status) String() string {
+	if s.Level == LevelNone && s.Remaining == 0 {
+		return ""
+	}
+
+	if s.Remaining <= 0 {
+		return fmt.Sprintf("SLA violado há %v", formatDuration(-s.Remaining))
+	}
+
+	return fmt.Sprintf("%.0f%% do SLA, restam %v", math.Floor(s.Elapsed), formatDuration(s.Remaining))
+}
+
+// formatDuration formats the duration in hours and minutes, such as "1h05min" or "35min"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+
+	if hours == 0 {
+		return fmt.Sprintf("%dmin", minutes)
+	}
+	return fmt.Sprintf("%dh%02dmin", hours, minutes)
+}
@@ -0,0 +1,93 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/config"
+)
+
+func TestEvaluate(t *testing.T) {
+	createdAt := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+	deadline := createdAt.Add(100 * time.Minute)
+	threshold := config.SLAThreshold{Normal: 50, Urgent: 80}
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		deadline  time.Time
+		elapsed   time.Duration
+		level     Level
+		text      string
+	}{
+		{"no deadline", createdAt, time.Time{}, 0, LevelNone, ""},
+		{"created", createdAt, deadline, 0, LevelNone, "0% do SLA, restam 1h40min"},
+		{"below normal", createdAt, deadline, 49*time.Minute + 59*time.Second, LevelNone, "49% do SLA, restam 50min"},
+		{"normal", createdAt, deadline, 50 * time.Minute, LevelNormal, "50% do SLA, restam 50min"},
+		{"below urgent", createdAt, deadline, 79*time.Minute + 59*time.Second, LevelNormal, "79% do SLA, restam 20min"},
+		{"urgent", createdAt, deadline, 80 * time.Minute, LevelUrgent, "80% do SLA, restam 20min"},
+		{"last second", createdAt, deadline, 99*time.Minute + 59*time.Second, LevelUrgent, "99% do SLA, restam 0min"},
+		{"deadline", createdAt, deadline, 100 * time.Minute, LevelCritical, "SLA violado há 0min"},
+		{"breached", createdAt, deadline, 165 * time.Minute, LevelCritical, "SLA violado há 1h05min"},
+		{"unknown creation", time.Time{}, deadline, 90 * time.Minute, LevelNone, "0% do SLA, restam 10min"},
+		{"unknown creation breached", time.Time{}, deadline, 110 * time.Minute, LevelCritical, "SLA violado há 10min"},
+		{"deadline before creation", deadline, createdAt, 100 * time.Minute, LevelCritical, "SLA violado há 1h40min"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := Evaluate(test.createdAt, test.deadline, createdAt.Add(test.elapsed), threshold)
+			if status.Level != test.level {
+				t.Errorf("expected level %v, got %v", test.level, status.Level)
+			}
+			if text := status.String(); text != test.text {
+				t.Errorf("expected %q, got %q", test.text, text)
+			}
+		})
+	}
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	createdAt := time.Date(2021, 1, 21, 14, 0, 0, 0, time.UTC)
+	deadline := createdAt.Add(100 * time.Minute)
+
+	tests := []struct {
+		threshold config.SLAThreshold
+		elapsed   time.Duration
+		level     Level
+	}{
+		{config.SLAThreshold{Normal: 10, Urgent: 20}, 9 * time.Minute, LevelNone},
+		{config.SLAThreshold{Normal: 10, Urgent: 20}, 10 * time.Minute, LevelNormal},
+		{config.SLAThreshold{Normal: 10, Urgent: 20}, 20 * time.Minute, LevelUrgent},
+		{config.SLAThreshold{Normal: 60, Urgent: 60}, 59 * time.Minute, LevelNone},
+		{config.SLAThreshold{Normal: 60, Urgent: 60}, 60 * time.Minute, LevelUrgent},
+		{config.SLAThreshold{Normal: 100, Urgent: 100}, 99 * time.Minute, LevelNone},
+		{config.SLAThreshold{Normal: 100, Urgent: 100}, 100 * time.Minute, LevelCritical},
+	}
+
+	for _, test := range tests {
+		if status := Evaluate(createdAt, deadline, createdAt.Add(test.elapsed), test.threshold); status.Level != test.level {
+			t.Errorf("with %+v after %v expected level %v, got %v", test.threshold, test.elapsed, test.level, status.Level)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0min"},
+		{29 * time.Second, "0min"},
+		{30 * time.Second, "1min"},
+		{59 * time.Minute, "59min"},
+		{time.Hour, "1h00min"},
+		{26*time.Hour + 5*time.Minute, "26h05min"},
+	}
+
+	for _, test := range tests {
+		if formatted := formatDuration(test.duration); formatted != test.expected {
+			t.Errorf("expected %v to be formatted as %q, got %q", test.duration, test.expected, formatted)
+		}
+	}
+}
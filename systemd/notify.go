@@ -0,0 +1,56 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States sent to systemd. See https://www.freedesktop.org/software/systemd/man/sd_notify.html
+const (
+	// Ready tells that the program finished starting up
+	Ready string = "READY=1"
+	// Stopping tells that the program is shutting down
+	Stopping string = "STOPPING=1"
+	// Watchdog tells that the program is still alive
+	Watchdog string = "WATCHDOG=1"
+)
+
+// Notify sends the state to the service manager through the socket given in NOTIFY_SOCKET.
+// It does nothing when the program is not supervised by systemd.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// sockets starting with "@" are in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how often the watchdog should be notified, which is half of the timeout configured
+// with WatchdogSec in the service unit. It is 0 when the watchdog is not enabled for this process.
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	microseconds, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || microseconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(microseconds) * time.Microsecond / 2
+}
@@ -0,0 +1,62 @@
+package tracker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Load creates a tracker that keeps its records in the given state file, so that they survive restarts.
+// The records already present in the file are loaded. A missing file is not an error, it is created on the first change.
+// A corrupt file, such as one left truncated by a crash, is renamed to "<stateFile>.corrupt-<time>" and the tracker starts empty.
+func Load(stateFile string, escalationInterval time.Duration) (*Tracker, error) {
+	t := New(escalationInterval)
+	t.stateFile = stateFile
+
+	content, err := ioutil.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &t.records); err != nil {
+		corruptFile := stateFile + ".corrupt-" + time.Now().Format("20060102T150405")
+		logger.Warnf("The notification state file is corrupt, starting with an empty state. The file was moved to %v. %v", corruptFile, err)
+		if err := os.Rename(stateFile, corruptFile); err != nil {
+			logger.Warnf("Error moving the corrupt notification state file. %v", err)
+		}
+		t.records = nil
+	}
+	if t.records == nil {
+		t.records = make(map[string]map[string]*Record)
+	}
+
+	return t, nil
+}
+
+// save writes the records to the state file. It must be called with the mutex held.
+// The content is first written to a temporary file, so that the state file is never left half written.
+func (t *Tracker) save() {
+	if t.stateFile == "" {
+		return
+	}
+
+	content, err := json.MarshalIndent(t.records, "", "  ")
+	if err != nil {
+		logger.Errorf("Error encoding the notification state. %v", err)
+		return
+	}
+
+	temporaryFile := t.stateFile + ".tmp"
+	if err := ioutil.WriteFile(temporaryFile, content, 0666); err != nil {
+		logger.Errorf("Error writing the notification state. %v", err)
+		return
+	}
+
+	if err := os.Rename(temporaryFile, t.stateFile); err != nil {
+		logger.Errorf("Error writing the notification state. %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package tracker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadKeepsRecords(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2021, 1, 21, 9, 0, 0, 0, time.UTC)
+
+	first, err := Load(stateFile, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Due("incidentsWithoutOwner", Items([]string{"123"}), now)
+
+	second, err := Load(stateFile, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due := second.Due("incidentsWithoutOwner", Items([]string{"123"}), now.Add(time.Minute)); len(due) != 0 {
+		t.Errorf("expected the item notified before the restart not to be due, got %v", due)
+	}
+}
+
+func TestLoadCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	if err := ioutil.WriteFile(stateFile, []byte(`{"incidentsWithoutOwner": {"123": {"firstSe`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := Load(stateFile, time.Hour)
+	if err != nil {
+		t.Fatalf("expected the corrupt file to be discarded, got %v", err)
+	}
+	if due := tr.Due("incidentsWithoutOwner", Items([]string{"123"}), time.Now()); len(due) != 1 {
+		t.Errorf("expected the tracker to start empty, got %v", due)
+	}
+
+	corruptFiles, err := filepath.Glob(stateFile + ".corrupt-*")
+	if err != nil || len(corruptFiles) != 1 {
+		t.Fatalf("expected the corrupt file to be kept, got %v %v", corruptFiles, err)
+	}
+
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Errorf("expected the state file to be written again, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pedroppinheiro/cwnotifier/logging"
+)
+
+var logger = logging.New("tracker")
+
+// clearedRetention is how long the record of an item that is no longer found is kept
+const clearedRetention time.Duration = 30 * 24 * time.Hour
+
+// Tracker remembers which items (incidents, tasks, changes) were already notified for each notification type,
+// so that the same item is not notified on every check
+type Tracker struct {
+	escalationInterval time.Duration
+	stateFile          string
+
+	mutex sync.Mutex
+	// records holds, by notification type, the records of the items that were found
+	records map[string]map[string]*Record
+}
+
+// Record is what the tracker knows about an item
+type Record struct {
+	FirstSeen    time.Time  `json:"firstSeen"`
+	LastNotified time.Time  `json:"lastNotified"`
+	NotifyCount  int        `json:"notifyCount"`
+	Level        int        `json:"level,omitempty"`
+	ClearedAt    *time.Time `json:"clearedAt,omitempty"`
+}
+
+// Item is an item found for a notification type. Level indicates how urgent the item is,
+// an item whose level increases is notified immediately, regardless of the escalation interval
+type Item struct {
+	Key   string
+	Level int
+}
+
+// Items creates items with level 0 from the given keys
+func Items(keys []string) []Item {
+	items := make([]Item, len(keys))
+	for i, key := range keys {
+		items[i] = Item{Key: key}
+	}
+	return items
+}
+
+// New creates a tracker that notifies again the items that are still present after the escalation interval.
+// The tracker is kept only in memory.
+func New(escalationInterval time.Duration) *Tracker {
+	return &Tracker{
+		escalationInterval: escalationInterval,
+		records:            make(map[string]map[string]*Record),
+	}
+}
+
+// SetEscalationInterval changes the interval after which the items that are still present are notified again
+func (t *Tracker) SetEscalationInterval(escalationInterval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.escalationInterval = escalationInterval
+}
+
+// Due receives the items currently found for a notification type and returns the keys of the ones that should be notified:
+// items that were not seen before, items whose level increased and items that were last notified at least one escalation interval ago.
+// The returned items are considered notified at the given time.
+// Items that are no longer found are marked as cleared, so they are notified immediately if they appear again.
+func (t *Tracker) Due(notificationType string, items []Item, now time.Time) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	records, isPresent := t.records[notificationType]
+	if !isPresent {
+		records = make(map[string]*Record)
+		t.records[notificationType] = records
+	}
+
+	found := make(map[string]bool, len(items))
+	var due []string
+	changed := false
+
+	for _, item := range items {
+		if found[item.Key] {
+			continue
+		}
+		found[item.Key] = true
+
+		record, wasSeen := records[item.Key]
+		if !wasSeen || record.ClearedAt != nil {
+			record = &Record{FirstSeen: now, Level: item.Level}
+			records[item.Key] = record
+			changed = true
+		}
+
+		levelIncreased := item.Level > record.Level
+		if item.Level != record.Level {
+			record.Level = item.Level
+			changed = true
+		}
+
+		if record.NotifyCount == 0 || levelIncreased || !now.Before(record.LastNotified.Add(t.escalationInterval)) {
+			record.LastNotified = now
+			record.NotifyCount++
+			due = append(due, item.Key)
+			changed = true
+		}
+	}
+
+	for item, record := range records {
+		if found[item] {
+			continue
+		}
+
+		if record.ClearedAt == nil {
+			clearedAt := now
+			record.ClearedAt = &clearedAt
+			changed = true
+			logger.Infof("%v: %v is no longer present. It was first seen at %v and notified %v time(s).", notificationType, item, record.FirstSeen.Format(time.RFC3339), record.NotifyCount)
+		} else if now.Sub(*record.ClearedAt) > clearedRetention {
+			delete(records, item)
+			changed = true
+		}
+	}
+
+	if changed {
+		t.save()
+	}
+
+	return due
+}
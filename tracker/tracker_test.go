@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// step is a check of a notification type at the given time after the first one, with the items found and the ones expected to be due
+type step struct {
+	elapsed  time.Duration
+	items    []Item
+	expected []string
+}
+
+func runSteps(t *testing.T, tr *Tracker, notificationType string, steps []step) {
+	t.Helper()
+	start := time.Date(2021, 1, 21, 9, 0, 0, 0, time.UTC)
+	for i, s := range steps {
+		if due := tr.Due(notificationType, s.items, start.Add(s.elapsed)); !reflect.DeepEqual(due, s.expected) {
+			t.Errorf("step %v (%v): expected %v to be due, got %v", i, s.elapsed, s.expected, due)
+		}
+	}
+}
+
+func TestDueFirstSeenAndRepeated(t *testing.T) {
+	runSteps(t, New(15*time.Minute), "incidentsWithoutOwner", []step{
+		{0, Items([]string{"1", "2"}), []string{"1", "2"}},
+		{time.Minute, Items([]string{"1", "2"}), nil},
+		{2 * time.Minute, Items([]string{"2", "3"}), []string{"3"}},
+		{3 * time.Minute, Items([]string{"3", "3"}), nil},
+		{4 * time.Minute, nil, nil},
+	})
+}
+
+func TestDueEscalationInterval(t *testing.T) {
+	runSteps(t, New(15*time.Minute), "incidentsWithoutOwner", []step{
+		{0, Items([]string{"1"}), []string{"1"}},
+		{14*time.Minute + 59*time.Second, Items([]string{"1"}), nil},
+		{15 * time.Minute, Items([]string{"1"}), []string{"1"}},
+		{29 * time.Minute, Items([]string{"1"}), nil},
+		{30 * time.Minute, Items([]string{"1"}), []string{"1"}},
+	})
+}
+
+func TestDueLevelIncrease(t *testing.T) {
+	runSteps(t, New(time.Hour), "incidentsWithoutOwner", []step{
+		{0, []Item{{Key: "1", Level: 0}}, []string{"1"}},
+		{time.Minute, []Item{{Key: "1", Level: 1}}, []string{"1"}},
+		{2 * time.Minute, []Item{{Key: "1", Level: 1}}, nil},
+		{3 * time.Minute, []Item{{Key: "1", Level: 3}}, []string{"1"}},
+		{4 * time.Minute, []Item{{Key: "1", Level: 2}}, nil},
+		{5 * time.Minute, []Item{{Key: "1", Level: 3}}, []string{"1"}},
+	})
+}
+
+func TestDueForgetsResolvedItems(t *testing.T) {
+	tr := New(time.Hour)
+	runSteps(t, tr, "incidentsWithoutOwner", []step{
+		{0, Items([]string{"1", "2"}), []string{"1", "2"}},
+		{time.Minute, Items([]string{"2"}), nil},
+		{2 * time.Minute, Items([]string{"1", "2"}), []string{"1"}},
+		{3 * time.Minute, nil, nil},
+	})
+
+	if cleared := tr.records["incidentsWithoutOwner"]["1"].ClearedAt; cleared == nil {
+		t.Fatal("expected the item that is no longer found to be marked as cleared")
+	}
+
+	tr.Due("incidentsWithoutOwner", nil, time.Date(2021, 1, 21, 9, 3, 0, 0, time.UTC).Add(clearedRetention+time.Minute))
+	if records := tr.records["incidentsWithoutOwner"]; len(records) != 0 {
+		t.Errorf("expected the cleared items to be forgotten after %v, got %v", clearedRetention, records)
+	}
+}
+
+func TestDueKeepsNotificationTypesApart(t *testing.T) {
+	tr := New(time.Hour)
+	now := time.Date(2021, 1, 21, 9, 0, 0, 0, time.UTC)
+
+	tr.Due("Support/incidentsWithoutOwner", Items([]string{"1"}), now)
+	if due := tr.Due("Network/incidentsWithoutOwner", Items([]string{"1"}), now); !reflect.DeepEqual(due, []string{"1"}) {
+		t.Errorf("expected the item to be due for the other notification type, got %v", due)
+	}
+	if due := tr.Due("Support/incidentsWithoutOwner", Items([]string{"1"}), now.Add(time.Minute)); len(due) != 0 {
+		t.Errorf("expected the item not to be due again, got %v", due)
+	}
+}
+
+func TestSetEscalationInterval(t *testing.T) {
+	tr := New(time.Hour)
+	now := time.Date(2021, 1, 21, 9, 0, 0, 0, time.UTC)
+
+	tr.Due("incidentsWithoutOwner", Items([]string{"1"}), now)
+	tr.SetEscalationInterval(5 * time.Minute)
+	if due := tr.Due("incidentsWithoutOwner", Items([]string{"1"}), now.Add(5*time.Minute)); !reflect.DeepEqual(due, []string{"1"}) {
+		t.Errorf("expected the new escalation interval to be used, got %v", due)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build !notray
+// +build !notray
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getlantern/systray"
+	"github.com/pedroppinheiro/cwnotifier/assets"
+	"github.com/pedroppinheiro/cwnotifier/notifier"
+)
+
+// statusMenuItem shows the status of the connection with cherwell in the system tray
+var statusMenuItem *systray.MenuItem
+
+// trayExitCode is returned by runTray, it is only 0 when the program was stopped without errors
+var trayExitCode = 1
+
+// runTray runs the program in the system tray until the user quits it
+func runTray() int {
+	systray.Run(onReady, nil)
+	return trayExitCode
+}
+
+func onReady() {
+	defer systray.Quit()
+	defer recoverFromError()
+
+	ctx, quit := context.WithCancel(context.Background())
+	defer quit()
+
+	configureSystemtray(quit)
+	stopOnSignal(quit)
+
+	err := run(ctx, func() {})
+	if errors.Is(err, errCrashed) {
+		return
+	} else if err != nil {
+		logger.Panic(err)
+	}
+	trayExitCode = 0
+}
+
+// https://dev.to/osuka42/building-a-simple-system-tray-app-with-go-899
+func configureSystemtray(quit context.CancelFunc) {
+	systray.SetIcon(readAsset(assets.AppIcon))
+	systray.SetTitle("CWNotifier")
+	systray.SetTooltip("CWNotifier")
+
+	statusMenuItem = systray.AddMenuItem("Connecting...", "Status of the connection with cherwell")
+	statusMenuItem.Disable()
+
+	showLogMenuItem := systray.AddMenuItem("Show log", "Show the app's log")
+	showLogMenuItem.SetIcon(readAsset(assets.LogIcon))
+	go func() {
+		for {
+			<-showLogMenuItem.ClickedCh
+			if err := notifier.OpenFile(defaultLogName); err != nil {
+				logger.Errorf("An error occurred during show log menu action. %v", err)
+			}
+		}
+	}()
+
+	quitMenuItem := systray.AddMenuItem("Quit", "Quit the app")
+	quitMenuItem.SetIcon(readAsset(assets.QuitIcon))
+	go func() {
+		<-quitMenuItem.ClickedCh
+		logger.Infof("User requested to quit")
+		quit()
+	}()
+}
+
+// setConnectionStatus shows the status of the connection with cherwell in the system tray, when it is being used
+func setConnectionStatus(connected bool) {
+	if statusMenuItem == nil {
+		return
+	}
+
+	if connected {
+		systray.SetTooltip("CWNotifier")
+		statusMenuItem.SetTitle("Connected")
+	} else {
+		systray.SetTooltip("CWNotifier (disconnected)")
+		statusMenuItem.SetTitle("Disconnected")
+	}
+}
+
+func readAsset(name string) []byte {
+	content, err := assets.Read(name)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return content
+}
@@ -0,0 +1,12 @@
+//go:build notray
+// +build notray
+
+package main
+
+// runTray runs the program without the system tray, since this build does not include it
+func runTray() int {
+	return runHeadless()
+}
+
+// setConnectionStatus does nothing, since there is no system tray to show the status
+func setConnectionStatus(connected bool) {}